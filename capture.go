@@ -0,0 +1,458 @@
+package unknownconnect
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/capture"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrCaptureQueueFull is passed to CaptureConfig.OnError when a message isn't captured because too
+// many are already waiting to be written.
+var ErrCaptureQueueFull = errors.New("unknownconnect: capture queue is full")
+
+// CaptureFormat is the file format captured messages are written in.
+type CaptureFormat int
+
+const (
+	// CaptureJSONL writes one JSON object per line, with the payload encoded as base64.
+	CaptureJSONL CaptureFormat = iota
+	// CaptureDelimited writes size-delimited records in the protobuf wire format. Records larger
+	// than 64 MiB are neither written nor read.
+	CaptureDelimited
+)
+
+func (f CaptureFormat) extension() string {
+	if f == CaptureDelimited {
+		return ".delimited"
+	}
+	return ".jsonl"
+}
+
+// Default sizes of the ring of capture files and of the queue of messages waiting to be written.
+const (
+	defaultCaptureFileSize = 1 << 20
+	defaultCaptureFiles    = 8
+	defaultCaptureQueue    = 64
+)
+
+// maxCaptureRecordSize bounds the records of CaptureDelimited files, so that a corrupt size can't
+// make a reader allocate more than that.
+const maxCaptureRecordSize = 64 << 20
+
+// CaptureConfig configures where and how often messages with unknown fields are captured.
+type CaptureConfig struct {
+	// Dir is the directory capture files are written to. It is created if it doesn't exist.
+	Dir string
+	// Format is the format of the capture files.
+	Format CaptureFormat
+	// MaxFileSize is the size after which a new capture file is started. Defaults to 1 MiB.
+	MaxFileSize int64
+	// MaxFiles is the number of capture files kept. The oldest file is deleted when a new one would
+	// go over. Defaults to 8.
+	MaxFiles int
+	// Headers lists the headers that are captured along with messages. No headers are captured by
+	// default, as they often hold credentials.
+	Headers []string
+	// Key groups messages for sampling. Defaults to the procedure.
+	Key func(spec connect.Spec, header http.Header) string
+	// PerKey is the number of messages captured per key in every Interval, so a single noisy peer
+	// doesn't fill the capture files. Zero means no limit.
+	PerKey int
+	// Interval is the period over which PerKey applies. Defaults to a minute.
+	Interval time.Duration
+	// QueueSize is the number of captured messages waiting to be written. Files are written in the
+	// background, and messages captured while the queue is full are dropped, calling OnError with
+	// ErrCaptureQueueFull. Defaults to 64.
+	QueueSize int
+	// OnError is called when a message couldn't be captured, possibly from the goroutine writing
+	// the files. Capturing never fails an RPC.
+	OnError func(error)
+}
+
+// CapturedMessage is a message with unknown fields saved by a Capturer.
+type CapturedMessage struct {
+	Time      time.Time `json:"time"`
+	Procedure string    `json:"procedure"`
+	// Direction is "request" for messages received by a handler and "response" for messages
+	// received by a client.
+	Direction string      `json:"direction"`
+	Header    http.Header `json:"header,omitempty"`
+	// MessageType is the full name of the message type.
+	MessageType string `json:"message_type"`
+	// Payload is the message in the binary format, including its unknown fields.
+	Payload []byte `json:"payload"`
+}
+
+// Spec returns the parts of the connect.Spec of the RPC that are known from the capture.
+func (c *CapturedMessage) Spec() connect.Spec {
+	return connect.Spec{Procedure: c.Procedure, IsClient: c.Direction == "response"}
+}
+
+// Capturer saves messages with unknown fields to a bounded ring of rotating files, so that drift can
+// be reproduced later. Pass it to interceptors with WithCapture.
+type Capturer struct {
+	config CaptureConfig
+	now    func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	keys        map[string]int
+
+	// queueMu guards closed, so that nothing is sent on queue once it is closed
+	queueMu sync.RWMutex
+	closed  bool
+	queue   chan []byte
+	// done receives the error closing the last file once the queue is written
+	done chan error
+
+	// only used by the goroutine writing the queue
+	files []string
+	file  *os.File
+	size  int64
+	seq   int
+}
+
+// NewCapturer creates a Capturer writing to config.Dir. Numbering of capture files continues after
+// the ones already in the directory.
+func NewCapturer(config CaptureConfig) (*Capturer, error) {
+	if config.Dir == "" {
+		return nil, errors.New("unknownconnect: capture directory is required")
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = defaultCaptureFileSize
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = defaultCaptureFiles
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultCaptureQueue
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	files, err := captureFiles(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	c := &Capturer{
+		config: config,
+		now:    time.Now,
+		keys:   map[string]int{},
+		queue:  make(chan []byte, config.QueueSize),
+		done:   make(chan error, 1),
+		files:  files,
+	}
+	if len(files) > 0 {
+		c.seq = captureSeq(files[len(files)-1])
+	}
+	go c.run()
+	return c, nil
+}
+
+// Close writes the messages still waiting in the queue and closes the current capture file.
+// Messages captured after Close are dropped.
+func (c *Capturer) Close() error {
+	c.queueMu.Lock()
+	if c.closed {
+		c.queueMu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.queue)
+	c.queueMu.Unlock()
+	return <-c.done
+}
+
+// run writes the queued records until the queue is closed.
+func (c *Capturer) run() {
+	for record := range c.queue {
+		if err := c.write(record); err != nil {
+			c.fail(err)
+		}
+	}
+	var err error
+	if c.file != nil {
+		err = c.file.Close()
+		c.file = nil
+	}
+	c.done <- err
+}
+
+func (c *Capturer) capture(spec connect.Spec, header http.Header, msg proto.Message) {
+	key := spec.Procedure
+	if c.config.Key != nil {
+		key = c.config.Key(spec, header)
+	}
+	if !c.allow(key) {
+		return
+	}
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	captured := &CapturedMessage{
+		Time:        c.now(),
+		Procedure:   spec.Procedure,
+		Direction:   "request",
+		MessageType: string(msg.ProtoReflect().Descriptor().FullName()),
+		Payload:     payload,
+	}
+	if spec.IsClient {
+		captured.Direction = "response"
+	}
+	for _, name := range c.config.Headers {
+		if values := header.Values(name); len(values) > 0 {
+			if captured.Header == nil {
+				captured.Header = http.Header{}
+			}
+			captured.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	record, err := marshalCapture(captured, c.config.Format)
+	if err != nil {
+		c.fail(err)
+		return
+	}
+	c.enqueue(record)
+}
+
+// enqueue hands a record to the goroutine writing the capture files, unless the queue is full.
+func (c *Capturer) enqueue(record []byte) {
+	c.queueMu.RLock()
+	defer c.queueMu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.queue <- record:
+	default:
+		c.fail(ErrCaptureQueueFull)
+	}
+}
+
+// marshalCapture encodes a captured message as a record of a capture file in the given format.
+func marshalCapture(c *CapturedMessage, format CaptureFormat) ([]byte, error) {
+	if format != CaptureDelimited {
+		record, err := json.Marshal(c)
+		return append(record, '\n'), err
+	}
+	record := &capture.Record{
+		Time:        c.Time.UnixNano(),
+		Procedure:   c.Procedure,
+		Direction:   c.Direction,
+		MessageType: c.MessageType,
+		Payload:     c.Payload,
+	}
+	names := make([]string, 0, len(c.Header))
+	for name := range c.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range c.Header[name] {
+			record.Headers = append(record.Headers, &capture.Header{Name: name, Value: []byte(value)})
+		}
+	}
+	if size := proto.Size(record); size > maxCaptureRecordSize {
+		return nil, fmt.Errorf("unknownconnect: captured %s message is too large: %d bytes", c.MessageType, size)
+	}
+	var buf bytes.Buffer
+	if _, err := protodelim.MarshalTo(&buf, record); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshalCapture decodes a record of a CaptureDelimited file.
+func unmarshalCapture(record *capture.Record) *CapturedMessage {
+	c := &CapturedMessage{
+		Time:        time.Unix(0, record.Time),
+		Procedure:   record.Procedure,
+		Direction:   record.Direction,
+		MessageType: record.MessageType,
+		Payload:     record.Payload,
+	}
+	for _, h := range record.Headers {
+		if c.Header == nil {
+			c.Header = http.Header{}
+		}
+		c.Header[h.Name] = append(c.Header[h.Name], string(h.Value))
+	}
+	return c
+}
+
+// allow returns true if another message with the key may be captured in the current interval.
+func (c *Capturer) allow(key string) bool {
+	if c.config.PerKey <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now := c.now(); now.Sub(c.windowStart) >= c.config.Interval {
+		c.windowStart = now
+		clear(c.keys)
+	}
+	if c.keys[key] >= c.config.PerKey {
+		return false
+	}
+	c.keys[key]++
+	return true
+}
+
+func (c *Capturer) write(record []byte) error {
+	if c.file != nil && c.size > 0 && c.size+int64(len(record)) > c.config.MaxFileSize {
+		if err := c.file.Close(); err != nil {
+			return err
+		}
+		c.file = nil
+	}
+	if c.file == nil {
+		if err := c.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := c.file.Write(record)
+	c.size += int64(n)
+	return err
+}
+
+// rotate starts a new capture file and deletes the oldest ones that no longer fit in the ring.
+func (c *Capturer) rotate() error {
+	c.seq++
+	name := filepath.Join(c.config.Dir, fmt.Sprintf("capture-%06d%s", c.seq, c.config.Format.extension()))
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	c.file, c.size = f, 0
+	c.files = append(c.files, name)
+	for len(c.files) > c.config.MaxFiles {
+		if err := os.Remove(c.files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		c.files = c.files[1:]
+	}
+	return nil
+}
+
+func (c *Capturer) fail(err error) {
+	if c.config.OnError != nil {
+		c.config.OnError(err)
+	}
+}
+
+// captureFiles lists the capture files in dir, oldest first.
+func captureFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && captureSeq(e.Name()) > 0 {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return captureSeq(files[i]) < captureSeq(files[j])
+	})
+	return files, nil
+}
+
+// captureSeq returns the sequence number of a capture file, or zero if it isn't one.
+func captureSeq(name string) int {
+	var seq int
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	if _, err := fmt.Sscanf(base, "capture-%d", &seq); err != nil {
+		return 0
+	}
+	return seq
+}
+
+// ReadCaptures reads every message captured in dir by a Capturer, oldest first.
+func ReadCaptures(dir string) ([]CapturedMessage, error) {
+	files, err := captureFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	var captured []CapturedMessage
+	for _, name := range files {
+		format := CaptureJSONL
+		if filepath.Ext(name) == CaptureDelimited.extension() {
+			format = CaptureDelimited
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		r := NewCaptureReader(f, format)
+		for {
+			c, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			captured = append(captured, *c)
+		}
+		f.Close()
+	}
+	return captured, nil
+}
+
+// CaptureReader reads the messages of a single capture file.
+type CaptureReader struct {
+	r      *bufio.Reader
+	format CaptureFormat
+}
+
+// NewCaptureReader creates a CaptureReader reading a capture file of the given format from r.
+func NewCaptureReader(r io.Reader, format CaptureFormat) *CaptureReader {
+	return &CaptureReader{r: bufio.NewReader(r), format: format}
+}
+
+// Read returns the next captured message. io.EOF is returned when there are no more messages.
+func (r *CaptureReader) Read() (*CapturedMessage, error) {
+	if r.format == CaptureDelimited {
+		record := &capture.Record{}
+		err := protodelim.UnmarshalOptions{MaxSize: maxCaptureRecordSize}.UnmarshalFrom(r.r, record)
+		if err != nil {
+			return nil, err
+		}
+		return unmarshalCapture(record), nil
+	}
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		c := &CapturedMessage{}
+		if err := json.Unmarshal(line, c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+}
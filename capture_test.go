@@ -0,0 +1,200 @@
+package unknownconnect_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+func TestCapture(t *testing.T) {
+	// received by an old server: email is unknown
+	body, err := proto.Marshal(&new.User{Name: "bob", Email: "bob@example.com"})
+	require.NoError(t, err)
+	receive := func(t *testing.T, interceptor connect.Interceptor, client string) {
+		msg := &old.User{}
+		require.NoError(t, proto.Unmarshal(body, msg))
+		req := connect.NewRequest(msg)
+		req.Header().Set("X-Client", client)
+		req.Header().Set("Authorization", "secret")
+		unary := interceptor.WrapUnary(connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		}))
+		_, err := unary(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	formats := map[string]unknownconnect.CaptureFormat{
+		"jsonl":     unknownconnect.CaptureJSONL,
+		"delimited": unknownconnect.CaptureDelimited,
+	}
+	for name, format := range formats {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			capturer, err := unknownconnect.NewCapturer(unknownconnect.CaptureConfig{
+				Dir:     dir,
+				Format:  format,
+				Headers: []string{"x-client"},
+			})
+			require.NoError(t, err)
+			receive(t, unknownconnect.NewInterceptor(unknownconnect.WithCapture(capturer)), "a")
+			require.NoError(t, capturer.Close())
+
+			captured, err := unknownconnect.ReadCaptures(dir)
+			require.NoError(t, err)
+			require.Len(t, captured, 1)
+			c := captured[0]
+			assert.Equal(t, "request", c.Direction)
+			assert.Equal(t, "helloworld.old.User", c.MessageType)
+			assert.Equal(t, []string{"a"}, c.Header.Values("X-Client"))
+			assert.Empty(t, c.Header.Values("Authorization"))
+			assert.Equal(t, body, c.Payload)
+			assert.False(t, c.Time.IsZero())
+
+			var report *unknownconnect.Report
+			inspector := unknownconnect.NewInspector(unknownconnect.WithReportCallback(func(ctx context.Context, s connect.Spec, r *unknownconnect.Report) error {
+				report = r
+				return nil
+			}))
+			require.NoError(t, inspector.Replay(context.Background(), &c, &old.User{}))
+			require.NotNil(t, report)
+			require.Len(t, report.Fields, 1)
+			assert.Equal(t, protowire.Number(2), report.Fields[0].Number)
+		})
+	}
+
+	t.Run("per key", func(t *testing.T) {
+		dir := t.TempDir()
+		capturer, err := unknownconnect.NewCapturer(unknownconnect.CaptureConfig{
+			Dir:    dir,
+			PerKey: 2,
+			Key: func(spec connect.Spec, header http.Header) string {
+				return header.Get("X-Client")
+			},
+		})
+		require.NoError(t, err)
+		interceptor := unknownconnect.NewInterceptor(unknownconnect.WithCapture(capturer))
+		for i := 0; i < 5; i++ {
+			receive(t, interceptor, "noisy")
+		}
+		receive(t, interceptor, "quiet")
+		require.NoError(t, capturer.Close())
+
+		captured, err := unknownconnect.ReadCaptures(dir)
+		require.NoError(t, err)
+		assert.Len(t, captured, 3)
+	})
+
+	t.Run("ring", func(t *testing.T) {
+		dir := t.TempDir()
+		capturer, err := unknownconnect.NewCapturer(unknownconnect.CaptureConfig{
+			Dir:         dir,
+			Format:      unknownconnect.CaptureDelimited,
+			MaxFileSize: 1,
+			MaxFiles:    3,
+		})
+		require.NoError(t, err)
+		interceptor := unknownconnect.NewInterceptor(unknownconnect.WithCapture(capturer))
+		for i := 0; i < 5; i++ {
+			receive(t, interceptor, "a")
+		}
+		require.NoError(t, capturer.Close())
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		assert.Equal(t, []string{"capture-000003.delimited", "capture-000004.delimited", "capture-000005.delimited"}, names)
+
+		// a new capturer continues the numbering
+		capturer, err = unknownconnect.NewCapturer(unknownconnect.CaptureConfig{Dir: dir, Format: unknownconnect.CaptureDelimited, MaxFiles: 3})
+		require.NoError(t, err)
+		receive(t, unknownconnect.NewInterceptor(unknownconnect.WithCapture(capturer)), "a")
+		require.NoError(t, capturer.Close())
+		_, err = os.Stat(dir + "/capture-000006.delimited")
+		assert.NoError(t, err)
+		captured, err := unknownconnect.ReadCaptures(dir)
+		require.NoError(t, err)
+		assert.Len(t, captured, 3)
+	})
+
+	t.Run("rejected messages are not captured", func(t *testing.T) {
+		dir := t.TempDir()
+		capturer, err := unknownconnect.NewCapturer(unknownconnect.CaptureConfig{Dir: dir})
+		require.NoError(t, err)
+		msg := &old.User{}
+		require.NoError(t, proto.Unmarshal(body, msg))
+		unary := unknownconnect.NewInterceptor(
+			unknownconnect.WithCapture(capturer),
+			unknownconnect.WithUnknownLimits(unknownconnect.UnknownLimits{MaxBytesPerMessage: 1}),
+		).WrapUnary(connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		}))
+		_, err = unary(context.Background(), connect.NewRequest(msg))
+		require.ErrorIs(t, err, unknownconnect.ErrUnknownLimitExceeded)
+		require.NoError(t, capturer.Close())
+		captured, err := unknownconnect.ReadCaptures(dir)
+		require.NoError(t, err)
+		assert.Empty(t, captured)
+	})
+
+	t.Run("redacted procedures are not captured", func(t *testing.T) {
+		dir := t.TempDir()
+		capturer, err := unknownconnect.NewCapturer(unknownconnect.CaptureConfig{Dir: dir})
+		require.NoError(t, err)
+		receive(t, unknownconnect.NewInterceptor(
+			unknownconnect.WithCapture(capturer),
+			unknownconnect.WithRedaction(unknownconnect.Redaction{}),
+		), "a")
+		require.NoError(t, capturer.Close())
+		captured, err := unknownconnect.ReadCaptures(dir)
+		require.NoError(t, err)
+		assert.Empty(t, captured)
+	})
+}
+
+func TestCaptureReader(t *testing.T) {
+	t.Run("corrupt size", func(t *testing.T) {
+		r := unknownconnect.NewCaptureReader(bytes.NewReader([]byte("\xff\xff\xff\xff\xff\xff\xff\xff\x7f")), unknownconnect.CaptureDelimited)
+		_, err := r.Read()
+		var tooLarge *protodelim.SizeTooLargeError
+		assert.ErrorAs(t, err, &tooLarge)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		r := unknownconnect.NewCaptureReader(bytes.NewReader([]byte{0x05, 0x08}), unknownconnect.CaptureDelimited)
+		_, err := r.Read()
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+func TestInspector(t *testing.T) {
+	user := &new.User{Name: "bob"}
+	user.ProtoReflect().SetUnknown(protopack.Message{protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1)}.Marshal())
+	var called bool
+	inspector := unknownconnect.NewInspector(
+		unknownconnect.WithCallback(func(ctx context.Context, s connect.Spec, m proto.Message) error {
+			called = true
+			assert.Equal(t, "/svc/Method", s.Procedure)
+			return nil
+		}),
+		unknownconnect.WithDrop(),
+	)
+	require.NoError(t, inspector.Inspect(context.Background(), connect.Spec{Procedure: "/svc/Method"}, nil, user))
+	assert.True(t, called)
+	assert.False(t, unknownconnect.MessageHasUnknownFields(user.ProtoReflect()))
+}
@@ -0,0 +1,205 @@
+package unknownconnect
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// defaultChaosFields is the default maximum number of fields injected into a single message.
+const defaultChaosFields = 3
+
+// ChaosConfig configures the injection of unknown fields into outgoing messages.
+type ChaosConfig struct {
+	// Rate is the fraction of outgoing messages that get unknown fields, from 0 to 1.
+	Rate float64
+	// MaxFields is the maximum number of fields injected into each message. Defaults to 3.
+	MaxFields int
+	// Seed seeds the random choices, to make them reproducible. Zero picks a random seed.
+	Seed int64
+}
+
+// Injection lists the unknown fields injected into an outgoing message.
+type Injection struct {
+	Spec   connect.Spec
+	Fields []UnknownField
+}
+
+// Chaos injects random unknown fields into outgoing messages, to check that peers tolerate fields
+// they don't know about before a real schema change ships. Pass it to interceptors with WithChaos.
+type Chaos struct {
+	config ChaosConfig
+
+	mu       sync.Mutex
+	random   *rand.Rand
+	injected []Injection
+}
+
+// NewChaos creates a new Chaos.
+func NewChaos(config ChaosConfig) *Chaos {
+	if config.MaxFields <= 0 {
+		config.MaxFields = defaultChaosFields
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Chaos{config: config, random: rand.New(rand.NewSource(seed))}
+}
+
+// Injected returns every injection made so far.
+func (c *Chaos) Injected() []Injection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Injection(nil), c.injected...)
+}
+
+// inject adds unknown fields to msg, and to some of the messages nested in it, if msg is picked.
+// The fields are appended to the unknown fields the messages already have.
+func (c *Chaos) inject(spec connect.Spec, m any) {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.random.Float64() >= c.config.Rate {
+		return
+	}
+	injection := Injection{Spec: spec}
+	rangeMessages(msg.ProtoReflect(), nil, func(p path, msg protoreflect.Message) {
+		// the message itself always gets fields, nested messages only half of the time
+		if len(p) > 0 && c.random.Intn(2) == 0 {
+			return
+		}
+		unknown := msg.GetUnknown()
+		for i := 1 + c.random.Intn(c.config.MaxFields); i > 0; i-- {
+			num := c.unusedNumber(msg.Descriptor())
+			if num == 0 {
+				break
+			}
+			raw := c.field(num, 2)
+			_, typ, _ := protowire.ConsumeTag(raw)
+			injection.Fields = append(injection.Fields, UnknownField{
+				Path:   p.String(),
+				Parent: msg,
+				Number: num,
+				Type:   typ,
+				Raw:    raw,
+			})
+			unknown = append(unknown, raw...)
+		}
+		msg.SetUnknown(unknown)
+	})
+	c.injected = append(c.injected, injection)
+}
+
+// unusedNumber returns a random field number that md doesn't use, outside of its extension and
+// reserved ranges. Reserved numbers belong to removed fields, which peers may still know about. It
+// starts from a random number and moves on to the next one that is free, skipping whole ranges, so
+// messages that reserve most numbers don't take long. It returns zero if every number is taken.
+func (c *Chaos) unusedNumber(md protoreflect.MessageDescriptor) protowire.Number {
+	start := protowire.Number(1 + c.random.Int31n(int32(protowire.MaxValidNumber)))
+	num, wrapped := start, false
+	for !wrapped || num < start {
+		next := num + 1
+		switch {
+		case num >= protowire.FirstReservedNumber && num <= protowire.LastReservedNumber:
+			next = protowire.LastReservedNumber + 1
+		case md.Fields().ByNumber(num) != nil:
+			// try the next number
+		case rangeEnd(md.ExtensionRanges(), num) > 0:
+			next = rangeEnd(md.ExtensionRanges(), num)
+		case rangeEnd(md.ReservedRanges(), num) > 0:
+			next = rangeEnd(md.ReservedRanges(), num)
+		default:
+			return num
+		}
+		if next > protowire.MaxValidNumber {
+			next, wrapped = 1, true
+		}
+		num = next
+	}
+	return 0
+}
+
+// rangeEnd returns the end, exclusive, of the range holding num, or zero if none does.
+func rangeEnd(ranges protoreflect.FieldRanges, num protowire.Number) protowire.Number {
+	for i := 0; i < ranges.Len(); i++ {
+		if r := ranges.Get(i); num >= r[0] && num < r[1] {
+			return r[1]
+		}
+	}
+	return 0
+}
+
+// field returns a random field in the wire format. Length-delimited fields and groups contain
+// nested fields while depth is above zero.
+func (c *Chaos) field(num protowire.Number, depth int) []byte {
+	var b []byte
+	switch c.random.Intn(5) {
+	case 0:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		b = protowire.AppendVarint(b, c.random.Uint64()>>c.random.Intn(64))
+	case 1:
+		b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+		b = protowire.AppendFixed32(b, c.random.Uint32())
+	case 2:
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, c.random.Uint64())
+	case 3:
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		if depth > 0 && c.random.Intn(2) == 0 {
+			b = protowire.AppendBytes(b, c.fields(depth-1))
+		} else {
+			b = protowire.AppendString(b, fmt.Sprintf("unknownconnect-chaos-%d", c.random.Intn(1000)))
+		}
+	default:
+		b = protowire.AppendTag(b, num, protowire.StartGroupType)
+		if depth > 0 {
+			b = append(b, c.fields(depth-1)...)
+		}
+		b = protowire.AppendTag(b, num, protowire.EndGroupType)
+	}
+	return b
+}
+
+func (c *Chaos) fields(depth int) []byte {
+	var b []byte
+	for i := 1 + c.random.Intn(3); i > 0; i-- {
+		b = append(b, c.field(protowire.Number(1+c.random.Intn(100)), depth)...)
+	}
+	return b
+}
+
+// rangeMessages calls fn with msg and every message nested in it.
+func rangeMessages(msg protoreflect.Message, p path, fn func(p path, msg protoreflect.Message)) {
+	fn(p, msg)
+	for _, fd := range walkPlanFor(msg.Descriptor()).fields {
+		if !msg.Has(fd) {
+			continue
+		}
+		v := msg.Get(fd)
+		switch {
+		case fd.IsMap():
+			v.Map().Range(func(mk protoreflect.MapKey, mv protoreflect.Value) bool {
+				rangeMessages(mv.Message(), p.push(fd, wire.MapKey(mk)), fn)
+				return true
+			})
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				rangeMessages(list.Get(i).Message(), p.push(fd, fmt.Sprint(i)), fn)
+			}
+		default:
+			rangeMessages(v.Message(), p.push(fd, ""), fn)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package unknownconnect_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new/newconnect"
+	"github.com/sudorandom/unknownconnect-go/internal/protobuild"
+	"github.com/sudorandom/unknownconnect-go/unknownconnecttest"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type echoServer struct {
+	newconnect.UnimplementedUserManagementHandler
+}
+
+func (echoServer) NewUser(_ context.Context, req *connect.Request[new.NewUserRequest]) (*connect.Response[new.NewUserResponse], error) {
+	return connect.NewResponse(&new.NewUserResponse{User: req.Msg.User}), nil
+}
+
+func TestChaos(t *testing.T) {
+	recorder := unknownconnecttest.NewRecorder(t)
+	_, handler := newconnect.NewUserManagementHandler(echoServer{}, recorder.Option())
+	chaos := unknownconnect.NewChaos(unknownconnect.ChaosConfig{Rate: 1, MaxFields: 5, Seed: 1})
+	client := newconnect.NewUserManagementClient(
+		unknownconnecttest.NewClient(handler),
+		unknownconnecttest.URL,
+		connect.WithInterceptors(unknownconnect.NewInterceptor(unknownconnect.WithChaos(chaos))),
+	)
+
+	for i := 0; i < 10; i++ {
+		_, err := client.NewUser(context.Background(), connect.NewRequest(&new.NewUserRequest{
+			User:    &new.User{Name: "bob"},
+			MsgList: []*new.User{{Name: "alice"}},
+		}))
+		require.NoError(t, err, "the handler survives the injected fields")
+	}
+
+	injected := chaos.Injected()
+	seen := recorder.Seen()
+	require.Len(t, injected, 10)
+	require.Len(t, seen, 10)
+	for i := range injected {
+		assert.Equal(t, fieldKeys(injected[i].Fields), fieldKeys(seen[i].Report.Fields))
+		assert.Equal(t, "/helloworld.new.UserManagement/NewUser", injected[i].Spec.Procedure)
+	}
+}
+
+func TestChaosRate(t *testing.T) {
+	_, handler := newconnect.NewUserManagementHandler(echoServer{})
+	chaos := unknownconnect.NewChaos(unknownconnect.ChaosConfig{Rate: 0})
+	client := newconnect.NewUserManagementClient(
+		unknownconnecttest.NewClient(handler),
+		unknownconnecttest.URL,
+		connect.WithInterceptors(unknownconnect.NewInterceptor(unknownconnect.WithChaos(chaos))),
+	)
+	req := &new.NewUserRequest{User: &new.User{Name: "bob"}}
+	_, err := client.NewUser(context.Background(), connect.NewRequest(req))
+	require.NoError(t, err)
+	assert.Empty(t, chaos.Injected())
+	assert.False(t, unknownconnect.MessageHasUnknownFields(req.ProtoReflect()))
+}
+
+func TestChaosReserved(t *testing.T) {
+	// every number but 2 is either used or reserved
+	dp := protobuild.Message("Sparse", protobuild.Field("name", 1, protobuild.TypeString, ""))
+	dp.ReservedRange = []*descriptorpb.DescriptorProto_ReservedRange{{Start: proto.Int32(3), End: proto.Int32(int32(protowire.MaxValidNumber) + 1)}}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("sparse.proto"),
+		Package:     proto.String("sparse"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{dp},
+	}, nil)
+	require.NoError(t, err)
+
+	chaos := unknownconnect.NewChaos(unknownconnect.ChaosConfig{Rate: 1, MaxFields: 5, Seed: 1})
+	unary := unknownconnect.NewInterceptor(unknownconnect.WithChaos(chaos)).WrapUnary(func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+		return connect.NewResponse(dynamicpb.NewMessage(fd.Messages().Get(0))), nil
+	})
+	for i := 0; i < 10; i++ {
+		_, err := unary(context.Background(), connect.NewRequest(&new.NewUserRequest{}))
+		require.NoError(t, err)
+	}
+	injected := chaos.Injected()
+	require.Len(t, injected, 10)
+	for _, injection := range injected {
+		for _, f := range injection.Fields {
+			assert.Equal(t, protowire.Number(2), f.Number)
+		}
+	}
+}
+
+// fieldKeys lists fields as sorted "path#number:raw" strings.
+func fieldKeys(fields []unknownconnect.UnknownField) []string {
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		keys[i] = fmt.Sprintf("%s#%d:%x", f.Path, f.Number, f.Raw)
+	}
+	sort.Strings(keys)
+	return keys
+}
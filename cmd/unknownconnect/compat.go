@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/sudorandom/unknownconnect-go"
+)
+
+func runCompat(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("compat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: unknownconnect compat -old old.binpb -new new.binpb [flags]")
+		flags.PrintDefaults()
+	}
+	oldDescriptors := flags.String("old", "", "path to the binary FileDescriptorSet of the version peers currently run")
+	newDescriptors := flags.String("new", "", "path to the binary FileDescriptorSet of the version being deployed")
+	format := flags.String("format", "text", "output format: text, json or markdown")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if *oldDescriptors == "" || *newDescriptors == "" || flags.NArg() > 0 {
+		flags.Usage()
+		return exitError
+	}
+
+	var write func(*unknownconnect.CompatReport, io.Writer) error
+	switch *format {
+	case "text":
+		write = (*unknownconnect.CompatReport).WriteText
+	case "json":
+		write = (*unknownconnect.CompatReport).WriteJSON
+	case "markdown":
+		write = (*unknownconnect.CompatReport).WriteMarkdown
+	default:
+		fmt.Fprintf(stderr, "unsupported format %q\n", *format)
+		return exitError
+	}
+
+	oldFiles, err := loadFiles(*oldDescriptors)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	newFiles, err := loadFiles(*newDescriptors)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	report := unknownconnect.CompareFiles(oldFiles, newFiles)
+	if err := write(report, stdout); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if !report.Empty() {
+		return exitUnknown
+	}
+	return exitOK
+}
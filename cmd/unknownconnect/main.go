@@ -0,0 +1,61 @@
+// Command unknownconnect inspects protobuf payloads for unknown fields.
+//
+// Usage:
+//
+//	unknownconnect scan -descriptors set.binpb -message pkg.Message [-format binary] [file ...]
+//	unknownconnect compat -old old.binpb -new new.binpb [-format text]
+//
+// The scan command prints the unknown fields of payloads read from the given files, or from stdin
+// when no files are given. With -format capture or capture-delimited, it reads files written by a
+// Capturer in the JSONL or delimited format, and -message may be omitted. The compat command lists
+// the changes between two versions of a schema that peers on either version would see as unknown
+// fields. The exit status is 1 if any unknown fields or changes were found and 2 if an error
+// occurred.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	exitOK      = 0
+	exitUnknown = 1
+	exitError   = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
+	switch args[0] {
+	case "scan":
+		return runScan(args[1:], stdin, stdout, stderr)
+	case "compat":
+		return runCompat(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		usage(stderr)
+		return exitError
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `usage: unknownconnect <command> [flags]
+
+commands:
+  scan    print the unknown fields of captured payloads
+  compat  list changes between two versions of a schema
+
+Run "unknownconnect <command> -h" for the flags of a command.
+`)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func writeDescriptors(t *testing.T, files ...*descriptorpb.FileDescriptorProto) string {
+	t.Helper()
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: files})
+	require.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "set.binpb")
+	require.NoError(t, os.WriteFile(filename, b, 0o600))
+	return filename
+}
+
+func TestScan(t *testing.T) {
+	descriptors := writeDescriptors(t, protodesc.ToFileDescriptorProto(old.File_internal_proto_old_user_proto))
+	req := &new.NewUserRequest{
+		User:          &new.User{Name: "bob", Email: "bob@example.com"},
+		PrimativeList: []int32{1, 2},
+	}
+	body, err := proto.Marshal(req)
+	require.NoError(t, err)
+
+	scan := func(t *testing.T, stdin []byte, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"scan", "-descriptors", descriptors, "-message", "helloworld.old.NewUserRequest"}, args...)
+		code := run(args, bytes.NewReader(stdin), &stdout, &stderr)
+		return code, stdout.String(), stderr.String()
+	}
+
+	t.Run("binary", func(t *testing.T) {
+		code, stdout, stderr := scan(t, body)
+		assert.Equal(t, exitUnknown, code, stderr)
+		assert.Equal(t, strings.Join([]string{
+			"<stdin>: .: field 4 (bytes)",
+			`    4: "\x01\x02"`,
+			"<stdin>: user: field 2 (bytes)",
+			`    2: "bob@example.com"`,
+			"",
+		}, "\n"), stdout)
+	})
+	t.Run("without unknown fields", func(t *testing.T) {
+		clean, err := proto.Marshal(&old.NewUserRequest{User: &old.User{Name: "bob"}})
+		require.NoError(t, err)
+		code, stdout, stderr := scan(t, clean)
+		assert.Equal(t, exitOK, code, stderr)
+		assert.Empty(t, stdout)
+	})
+	t.Run("delimited file", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := protodelim.MarshalTo(&buf, &old.NewUserRequest{})
+		require.NoError(t, err)
+		offset := buf.Len()
+		_, err = protodelim.MarshalTo(&buf, &new.NewUserRequest{User: &new.User{Email: "bob@example.com"}})
+		require.NoError(t, err)
+		filename := filepath.Join(t.TempDir(), "payloads.bin")
+		require.NoError(t, os.WriteFile(filename, buf.Bytes(), 0o600))
+
+		code, stdout, stderr := scan(t, nil, "-format", "delimited", filename)
+		assert.Equal(t, exitUnknown, code, stderr)
+		assert.Contains(t, stdout, fmt.Sprintf("%s[record 1 @ offset %d]: user: field 2 (bytes)", filename, offset))
+	})
+	t.Run("base64", func(t *testing.T) {
+		stdin := "\n" + base64.StdEncoding.EncodeToString(body) + "\n"
+		code, stdout, stderr := scan(t, []byte(stdin), "-format", "base64")
+		assert.Equal(t, exitUnknown, code, stderr)
+		assert.Contains(t, stdout, "<stdin>:2: user: field 2 (bytes)")
+	})
+	t.Run("json", func(t *testing.T) {
+		code, stdout, stderr := scan(t, []byte(`{"user": {"name": "bob", "email": "bob@example.com"}, "msgList": []}`), "-format", "json")
+		assert.Equal(t, exitUnknown, code, stderr)
+		assert.Equal(t, strings.Join([]string{
+			`<stdin>: user: key "email"`,
+			`    "bob@example.com"`,
+			`<stdin>: .: key "msgList"`,
+			`    []`,
+			"",
+		}, "\n"), stdout)
+	})
+	t.Run("capture", func(t *testing.T) {
+		dir := t.TempDir()
+		capturer, err := unknownconnect.NewCapturer(unknownconnect.CaptureConfig{Dir: dir, Format: unknownconnect.CaptureDelimited})
+		require.NoError(t, err)
+		msg := &old.NewUserRequest{}
+		require.NoError(t, proto.Unmarshal(body, msg))
+		unary := unknownconnect.NewInterceptor(unknownconnect.WithCapture(capturer)).WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		})
+		_, err = unary(context.Background(), connect.NewRequest(msg))
+		require.NoError(t, err)
+		require.NoError(t, capturer.Close())
+		filename := filepath.Join(dir, "capture-000001.delimited")
+
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"scan", "-descriptors", descriptors, "-format", "capture-delimited", filename}, nil, &stdout, &stderr)
+		assert.Equal(t, exitUnknown, code, stderr.String())
+		assert.Contains(t, stdout.String(), filename+"[capture 0: request]: user: field 2 (bytes)")
+
+		f, err := os.Open(filename)
+		require.NoError(t, err)
+		defer f.Close()
+		stdout.Reset()
+		code = run([]string{"scan", "-descriptors", descriptors, "-format", "capture-delimited"}, f, &stdout, &stderr)
+		assert.Equal(t, exitUnknown, code, stderr.String())
+		assert.Contains(t, stdout.String(), "<stdin>[capture 0: request]: user: field 2 (bytes)")
+	})
+	t.Run("invalid payload", func(t *testing.T) {
+		code, _, stderr := scan(t, []byte{0xff})
+		assert.Equal(t, exitError, code)
+		assert.NotEmpty(t, stderr)
+	})
+	t.Run("unknown message", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"scan", "-descriptors", descriptors, "-message", "helloworld.old.Nope"}, nil, &stdout, &stderr)
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr.String(), "helloworld.old.Nope")
+	})
+}
+
+func TestCompat(t *testing.T) {
+	newFile := protodesc.ToFileDescriptorProto(new.File_internal_proto_new_user_proto)
+	oldFile := proto.Clone(newFile).(*descriptorpb.FileDescriptorProto)
+	for _, msg := range oldFile.MessageType {
+		if msg.GetName() == "User" {
+			msg.Field = msg.Field[:1]
+		}
+	}
+	oldDescriptors := writeDescriptors(t, oldFile)
+	newDescriptors := writeDescriptors(t, newFile)
+
+	t.Run("changes", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"compat", "-old", oldDescriptors, "-new", newDescriptors}, nil, &stdout, &stderr)
+		assert.Equal(t, exitUnknown, code, stderr.String())
+		assert.Equal(t, "/helloworld.new.UserManagement/NewUser\n  helloworld.new.User: field added: email = 2 (string)\n", stdout.String())
+	})
+	t.Run("no changes", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"compat", "-old", newDescriptors, "-new", newDescriptors, "-format", "markdown"}, nil, &stdout, &stderr)
+		assert.Equal(t, exitOK, code, stderr.String())
+		assert.Equal(t, "No changes found.\n", stdout.String())
+	})
+	t.Run("bad format", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"compat", "-old", oldDescriptors, "-new", newDescriptors, "-format", "yaml"}, nil, &stdout, &stderr)
+		assert.Equal(t, exitError, code)
+	})
+}
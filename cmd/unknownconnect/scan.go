@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sudorandom/unknownconnect-go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	formatBinary           = "binary"
+	formatDelimited        = "delimited"
+	formatBase64           = "base64"
+	formatJSON             = "json"
+	formatCapture          = "capture"
+	formatCaptureDelimited = "capture-delimited"
+)
+
+type scanner struct {
+	files  *protoregistry.Files
+	md     protoreflect.MessageDescriptor
+	format string
+	stdout io.Writer
+	found  bool
+}
+
+func runScan(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: unknownconnect scan -descriptors set.binpb -message pkg.Message [flags] [file ...]")
+		flags.PrintDefaults()
+	}
+	descriptors := flags.String("descriptors", "", "path to a binary FileDescriptorSet, like the output of `buf build -o set.binpb`")
+	message := flags.String("message", "", "full name of the message type of the payloads, optional for captures")
+	format := flags.String("format", formatBinary, "payload format: binary, delimited, base64 (one payload per line), json, capture (JSONL files written by a Capturer) or capture-delimited")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if *descriptors == "" || (*message == "" && *format != formatCapture && *format != formatCaptureDelimited) {
+		flags.Usage()
+		return exitError
+	}
+	switch *format {
+	case formatBinary, formatDelimited, formatBase64, formatJSON, formatCapture, formatCaptureDelimited:
+	default:
+		fmt.Fprintf(stderr, "unsupported format %q\n", *format)
+		return exitError
+	}
+
+	files, err := loadFiles(*descriptors)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	var md protoreflect.MessageDescriptor
+	if *message != "" {
+		md, err = findMessage(files, *message)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+	}
+
+	s := &scanner{files: files, md: md, format: *format, stdout: stdout}
+	if flags.NArg() == 0 {
+		err = s.scan("<stdin>", stdin)
+	}
+	for _, filename := range flags.Args() {
+		if err != nil {
+			break
+		}
+		err = s.scanFile(filename, stdin)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	if s.found {
+		return exitUnknown
+	}
+	return exitOK
+}
+
+func (s *scanner) scanFile(filename string, stdin io.Reader) error {
+	if filename == "-" {
+		return s.scan("<stdin>", stdin)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.scan(filename, f)
+}
+
+func (s *scanner) scan(source string, r io.Reader) error {
+	switch s.format {
+	case formatCapture:
+		return s.scanCapture(source, r, unknownconnect.CaptureJSONL)
+	case formatCaptureDelimited:
+		return s.scanCapture(source, r, unknownconnect.CaptureDelimited)
+	case formatDelimited:
+		reader := unknownconnect.NewDelimitedReader(r)
+		for {
+			report, err := reader.Read(dynamicpb.NewMessage(s.md))
+			if errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("%s: %w", source, err)
+			}
+			if report != nil {
+				s.print(fmt.Sprintf("%s[record %d @ offset %d]", source, report.Record, report.Offset), &report.Report)
+			}
+		}
+	case formatBase64:
+		lines := bufio.NewScanner(r)
+		lines.Buffer(nil, 64<<20)
+		for lineNo := 1; lines.Scan(); lineNo++ {
+			line := strings.TrimSpace(lines.Text())
+			if line == "" {
+				continue
+			}
+			b, err := decodeBase64(line)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", source, lineNo, err)
+			}
+			if err := s.scanBinary(fmt.Sprintf("%s:%d", source, lineNo), b); err != nil {
+				return err
+			}
+		}
+		return lines.Err()
+	default:
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		if s.format == formatJSON {
+			return s.scanJSON(source, b)
+		}
+		return s.scanBinary(source, b)
+	}
+}
+
+// scanCapture scans messages saved by an unknownconnect.Capturer in the given format. Unless
+// -message is given, the payloads are decoded as the message type they were captured with.
+func (s *scanner) scanCapture(source string, r io.Reader, format unknownconnect.CaptureFormat) error {
+	reader := unknownconnect.NewCaptureReader(r, format)
+	for i := 0; ; i++ {
+		captured, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		md := s.md
+		if md == nil {
+			if md, err = findMessage(s.files, captured.MessageType); err != nil {
+				return fmt.Errorf("%s[capture %d]: %w", source, i, err)
+			}
+		}
+		msg := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(captured.Payload, msg); err != nil {
+			return fmt.Errorf("%s[capture %d]: %w", source, i, err)
+		}
+		what := captured.Direction
+		if captured.Procedure != "" {
+			what = captured.Procedure + " " + what
+		}
+		s.print(fmt.Sprintf("%s[capture %d: %s]", source, i, what), unknownconnect.NewReport(msg))
+	}
+}
+
+func (s *scanner) scanBinary(source string, b []byte) error {
+	msg := dynamicpb.NewMessage(s.md)
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	s.print(source, unknownconnect.NewReport(msg))
+	return nil
+}
+
+// scanJSON finds unknown keys, since JSON has no way to carry unknown fields of the binary format.
+func (s *scanner) scanJSON(source string, b []byte) error {
+	report, err := unknownconnect.UnmarshalJSON(b, dynamicpb.NewMessage(s.md))
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	s.print(source, report)
+	return nil
+}
+
+func (s *scanner) print(source string, report *unknownconnect.Report) {
+	for _, f := range report.Fields {
+		s.found = true
+		p := f.Path
+		if p == "" {
+			p = "."
+		}
+		kind := ""
+		if f.Kind != unknownconnect.KindNew {
+			kind = f.Kind.String()
+		}
+		if f.Name != "" {
+			key := fmt.Sprintf("key %q", f.Name)
+			if kind != "" {
+				key += " (" + kind + ")"
+			}
+			fmt.Fprintf(s.stdout, "%s: %s: %s\n    %s\n", source, p, key, f.Raw)
+			continue
+		}
+		if f.Number == 0 {
+			fmt.Fprintf(s.stdout, "%s: %s: malformed unknown fields: %q\n", source, p, f.Raw)
+			continue
+		}
+		details := unknownconnect.WireTypeName(f.Type)
+		if kind != "" {
+			details += ", " + kind
+		}
+		fmt.Fprintf(s.stdout, "%s: %s: field %d (%s)\n", source, p, f.Number, details)
+		for _, line := range strings.Split(unknownconnect.FormatRaw(f.Raw), "\n") {
+			fmt.Fprintf(s.stdout, "    %s\n", line)
+		}
+	}
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	_, err := base64.StdEncoding.DecodeString(s)
+	return nil, err
+}
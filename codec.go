@@ -0,0 +1,173 @@
+package unknownconnect
+
+import (
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+var _ connect.Codec = (*ProtoCodec)(nil)
+
+// ProtoCodec is a connect.Codec for the binary protobuf format that checks the raw bytes for
+// unknown fields while unmarshalling. Interceptors created with WithProtoCodec then skip the
+// reflective walk for messages the codec found to be free of unknown fields, which is most of them.
+// Pass the same codec to the client or handler with connect.WithCodec and to the interceptor with
+// WithProtoCodec.
+type ProtoCodec struct {
+	clean messageTable[struct{}]
+}
+
+// NewProtoCodec creates a new ProtoCodec.
+func NewProtoCodec() *ProtoCodec {
+	return &ProtoCodec{}
+}
+
+// Name implements connect.Codec.
+func (c *ProtoCodec) Name() string {
+	return "proto"
+}
+
+// Marshal implements connect.Codec.
+func (c *ProtoCodec) Marshal(m any) ([]byte, error) {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil, errNotProto(m)
+	}
+	return proto.Marshal(msg)
+}
+
+// Unmarshal implements connect.Codec.
+func (c *ProtoCodec) Unmarshal(data []byte, m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errNotProto(m)
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+	if !wireHasUnknownFields(msg.ProtoReflect().Descriptor(), data) {
+		c.clean.put(msg, struct{}{})
+	}
+	return nil
+}
+
+// isClean returns true if msg was unmarshalled by the codec and had no unknown fields, and forgets
+// about the message.
+func (c *ProtoCodec) isClean(msg proto.Message) bool {
+	_, ok := c.clean.take(msg)
+	return ok
+}
+
+// wireHasUnknownFields returns true if unmarshalling b into a message of the given type leaves any
+// unknown fields, including in nested messages. Malformed input counts as unknown so the caller
+// falls back to looking at the message itself.
+func wireHasUnknownFields(md protoreflect.MessageDescriptor, b []byte) bool {
+	fields := md.Fields()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return true
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return true
+		}
+		value := b[:n]
+		b = b[n:]
+
+		fd := fields.ByNumber(num)
+		if fd == nil && md.ExtensionRanges().Has(num) {
+			// registered extensions are parsed like fields, and can hold unknown fields too
+			if xt, err := protoregistry.GlobalTypes.FindExtensionByNumber(md.FullName(), num); err == nil {
+				fd = xt.TypeDescriptor()
+			}
+		}
+		if fd == nil {
+			return true
+		}
+		if !wire.Accepts(fd, typ) {
+			return true
+		}
+		switch {
+		case typ == protowire.BytesType && fd.Message() != nil:
+			v, _ := protowire.ConsumeBytes(value)
+			if wireHasUnknownFields(fd.Message(), v) {
+				return true
+			}
+		case typ == protowire.StartGroupType:
+			v, _ := protowire.ConsumeGroup(num, value)
+			if wireHasUnknownFields(fd.Message(), v) {
+				return true
+			}
+		case fd.Enum() != nil && wire.IsClosedEnum(fd.Enum()):
+			// closed enums keep values they don't know in the unknown fields, and the Go runtime
+			// keeps them as set, which reports list as drift either way
+			if wireHasUnknownEnumValue(fd.Enum(), typ, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func wireHasUnknownEnumValue(ed protoreflect.EnumDescriptor, typ protowire.Type, b []byte) bool {
+	if typ == protowire.BytesType {
+		b, _ = protowire.ConsumeBytes(b)
+	}
+	for len(b) > 0 {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return true
+		}
+		if ed.Values().ByNumber(protoreflect.EnumNumber(int32(v))) == nil {
+			return true
+		}
+		b = b[n:]
+	}
+	return false
+}
+
+// maxMessageTableGeneration is the number of entries after which a messageTable starts forgetting
+// its oldest entries.
+const maxMessageTableGeneration = 4096
+
+// messageTable passes results from a codec's Unmarshal to the interceptor that inspects the
+// message afterwards. It forgets the oldest entries once it gets too large, so results for messages
+// that never reach an interceptor don't pile up. The zero value is ready to use.
+type messageTable[T any] struct {
+	mu       sync.Mutex
+	current  map[proto.Message]T
+	previous map[proto.Message]T
+}
+
+func (t *messageTable[T]) put(msg proto.Message, v T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.current) >= maxMessageTableGeneration {
+		t.previous, t.current = t.current, nil
+	}
+	if t.current == nil {
+		t.current = map[proto.Message]T{}
+	}
+	t.current[msg] = v
+}
+
+func (t *messageTable[T]) take(msg proto.Message) (T, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if v, ok := t.current[msg]; ok {
+		delete(t.current, msg)
+		return v, true
+	}
+	v, ok := t.previous[msg]
+	if ok {
+		delete(t.previous, msg)
+	}
+	return v, ok
+}
@@ -0,0 +1,180 @@
+package unknownconnect_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/legacy"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+func TestProtoCodec(t *testing.T) {
+	mustMarshal := func(m proto.Message) []byte {
+		b, err := proto.Marshal(m)
+		require.NoError(t, err)
+		return b
+	}
+	testCases := []struct {
+		name       string
+		body       []byte
+		msg        proto.Message
+		hasUnknown bool
+	}{
+		{
+			name: "without unknown fields",
+			body: mustMarshal(&new.NewUserRequest{User: &new.User{Name: "bob"}}),
+			msg:  &old.NewUserRequest{},
+		},
+		{
+			name:       "with unknown field",
+			body:       mustMarshal(&new.NewUserRequest{PrimativeList: []int32{1, 2}}),
+			msg:        &old.NewUserRequest{},
+			hasUnknown: true,
+		},
+		{
+			name:       "with nested unknown field",
+			body:       mustMarshal(&new.NewUserRequest{User: &new.User{Name: "bob", Email: "bob@example.com"}}),
+			msg:        &old.NewUserRequest{},
+			hasUnknown: true,
+		},
+		{
+			name: "with nested msg list and map",
+			body: mustMarshal(&new.NewUserRequest{
+				MsgList:       []*new.User{{Name: "bob1"}, {Name: "bob2"}},
+				MsgMap:        map[int32]*new.User{1: {Name: "bob1"}},
+				PrimativeList: []int32{1, 2},
+				PrimativeMap:  map[int32]int32{1: 2},
+			}),
+			msg: &new.NewUserRequest{},
+		},
+		{
+			name: "with unknown field in msg map",
+			body: protopack.Message{
+				protopack.Tag{Number: 3, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+					protopack.Tag{Number: 1, Type: protopack.VarintType}, protopack.Varint(1),
+					protopack.Tag{Number: 2, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+						protopack.Tag{Number: 9, Type: protopack.BytesType}, protopack.String("nickname"),
+					}},
+				}},
+			}.Marshal(),
+			msg:        &new.NewUserRequest{},
+			hasUnknown: true,
+		},
+		{
+			name: "with unpacked repeated scalar",
+			body: protopack.Message{
+				protopack.Tag{Number: 4, Type: protopack.VarintType}, protopack.Varint(1),
+				protopack.Tag{Number: 4, Type: protopack.VarintType}, protopack.Varint(2),
+			}.Marshal(),
+			msg: &new.NewUserRequest{},
+		},
+		{
+			name: "with registered extension",
+			body: mustMarshal(func() proto.Message {
+				plugin := &legacy.Plugin{Name: proto.String("lint")}
+				proto.SetExtension(plugin, legacy.E_Config, &legacy.Config{Value: proto.String("strict")})
+				return plugin
+			}()),
+			msg: &legacy.Plugin{},
+		},
+		{
+			name: "with unknown field in registered extension",
+			body: protopack.Message{
+				protopack.Tag{Number: 100, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+					protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("strict"),
+					protopack.Tag{Number: 9, Type: protopack.VarintType}, protopack.Varint(1),
+				}},
+			}.Marshal(),
+			msg:        &legacy.Plugin{},
+			hasUnknown: true,
+		},
+		{
+			name:       "with registered extension of the wrong wire type",
+			body:       protopack.Message{protopack.Tag{Number: 100, Type: protopack.VarintType}, protopack.Varint(1)}.Marshal(),
+			msg:        &legacy.Plugin{},
+			hasUnknown: true,
+		},
+		{
+			name:       "with wrong wire type",
+			body:       protopack.Message{protopack.Tag{Number: 1, Type: protopack.Fixed32Type}, protopack.Int32(42)}.Marshal(),
+			msg:        &new.User{},
+			hasUnknown: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			codec := unknownconnect.NewProtoCodec()
+			var called bool
+			interceptor := unknownconnect.NewInterceptor(
+				unknownconnect.WithProtoCodec(codec),
+				unknownconnect.WithCallback(func(context.Context, connect.Spec, proto.Message) error {
+					called = true
+					return nil
+				}))
+			handler := interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+				return conn.Receive(tc.msg)
+			})
+
+			require.NoError(t, handler(context.Background(), &fakeHandlerConn{body: tc.body, codec: codec}))
+			assert.Equal(t, tc.hasUnknown, unknownconnect.MessageHasUnknownFields(tc.msg.ProtoReflect()))
+			assert.Equal(t, tc.hasUnknown, called)
+		})
+	}
+	t.Run("not a proto message", func(t *testing.T) {
+		codec := unknownconnect.NewProtoCodec()
+		assert.Error(t, codec.Unmarshal(nil, "bob"))
+		_, err := codec.Marshal("bob")
+		assert.Error(t, err)
+	})
+}
+
+func BenchmarkUnmarshalAndInspect(b *testing.B) {
+	for _, size := range []int{10, 1000} {
+		req := &new.NewUserRequest{User: &new.User{Name: "bob", Email: "bob@example.com"}}
+		for i := 0; i < size; i++ {
+			user := &new.User{Name: fmt.Sprint("bob", i), Email: "bob@example.com"}
+			req.MsgList = append(req.MsgList, user)
+			req.PrimativeList = append(req.PrimativeList, int32(i))
+		}
+		body, err := proto.Marshal(req)
+		require.NoError(b, err)
+
+		callback := unknownconnect.WithCallback(func(context.Context, connect.Spec, proto.Message) error {
+			return nil
+		})
+		next := func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		}
+		run := func(b *testing.B, unmarshal func([]byte, any) error, unary connect.UnaryFunc) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				msg := &new.NewUserRequest{}
+				if err := unmarshal(body, msg); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := unary(context.Background(), connect.NewRequest(msg)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+
+		b.Run(fmt.Sprintf("size=%d/walk", size), func(b *testing.B) {
+			unmarshal := func(data []byte, m any) error {
+				return proto.Unmarshal(data, m.(proto.Message))
+			}
+			run(b, unmarshal, unknownconnect.NewInterceptor(callback).WrapUnary(next))
+		})
+		b.Run(fmt.Sprintf("size=%d/codec", size), func(b *testing.B) {
+			codec := unknownconnect.NewProtoCodec()
+			run(b, codec.Unmarshal, unknownconnect.NewInterceptor(callback, unknownconnect.WithProtoCodec(codec)).WrapUnary(next))
+		})
+	}
+}
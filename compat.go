@@ -0,0 +1,372 @@
+package unknownconnect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ChangeKind is the kind of a schema change found by CompareDescriptorSets.
+type ChangeKind string
+
+const (
+	// FieldAdded is a field only the new schema has. Peers with the old schema see it as unknown.
+	FieldAdded ChangeKind = "field_added"
+	// FieldRemoved is a field only the old schema has. Peers with the new schema see it as unknown.
+	FieldRemoved ChangeKind = "field_removed"
+	// FieldRenumbered is a field with the same name but a different number. On the wire, it is a
+	// removed field and an added one.
+	FieldRenumbered ChangeKind = "field_renumbered"
+	// FieldRenamed is a field with the same number but a different name. It is still the same field
+	// in the binary format, but not in JSON.
+	FieldRenamed ChangeKind = "field_renamed"
+	// FieldTypeChanged is a field whose type changed in a way that isn't wire compatible.
+	FieldTypeChanged ChangeKind = "field_type_changed"
+	// EnumValueAdded is an enum value only the new schema has.
+	EnumValueAdded ChangeKind = "enum_value_added"
+)
+
+// Change is a single difference between two versions of a message or enum.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Type is the full name of the message or enum that changed.
+	Type string `json:"type"`
+	// Name is the name of the field or enum value that changed, as it is in the new schema.
+	Name string `json:"name"`
+	// OldName is the name of a renamed field in the old schema.
+	OldName   string `json:"old_name,omitempty"`
+	OldNumber int32  `json:"old_number,omitempty"`
+	NewNumber int32  `json:"new_number,omitempty"`
+	OldType   string `json:"old_type,omitempty"`
+	NewType   string `json:"new_type,omitempty"`
+}
+
+// MethodChanges lists the changes to every message and enum reachable from a method's request or
+// response.
+type MethodChanges struct {
+	// Procedure is the Connect procedure of the method, like "/greet.v1.GreetService/Greet".
+	Procedure string   `json:"procedure"`
+	Changes   []Change `json:"changes"`
+}
+
+// CompatReport is the result of comparing two versions of a schema.
+type CompatReport struct {
+	Methods []MethodChanges `json:"methods"`
+}
+
+// Empty returns true if no method has any changes.
+func (r *CompatReport) Empty() bool {
+	return len(r.Methods) == 0
+}
+
+// CompareDescriptorSets compares an old and a new version of a schema and reports, for every method
+// of every service in the new schema, the changes to the messages and enums the method uses. Types
+// are matched between the two versions by their full name and types that only exist in one version
+// are skipped. Fields are matched by number, as peers do on the wire. Extensions are not compared.
+func CompareDescriptorSets(oldSet, newSet *descriptorpb.FileDescriptorSet) (*CompatReport, error) {
+	oldFiles, err := protodesc.NewFiles(oldSet)
+	if err != nil {
+		return nil, fmt.Errorf("old descriptors: %w", err)
+	}
+	newFiles, err := protodesc.NewFiles(newSet)
+	if err != nil {
+		return nil, fmt.Errorf("new descriptors: %w", err)
+	}
+	return CompareFiles(oldFiles, newFiles), nil
+}
+
+// CompareFiles is like CompareDescriptorSets but works on already resolved files.
+func CompareFiles(oldFiles, newFiles *protoregistry.Files) *CompatReport {
+	report := &CompatReport{}
+	cache := map[protoreflect.FullName][]Change{}
+	newFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				method := methods.Get(j)
+				var changes []Change
+				seen := map[protoreflect.FullName]bool{}
+				changes = compareMessage(oldFiles, method.Input(), seen, cache, changes)
+				changes = compareMessage(oldFiles, method.Output(), seen, cache, changes)
+				if len(changes) > 0 {
+					report.Methods = append(report.Methods, MethodChanges{
+						Procedure: fmt.Sprintf("/%s/%s", services.Get(i).FullName(), method.Name()),
+						Changes:   changes,
+					})
+				}
+			}
+		}
+		return true
+	})
+	sort.Slice(report.Methods, func(i, j int) bool {
+		return report.Methods[i].Procedure < report.Methods[j].Procedure
+	})
+	return report
+}
+
+func compareMessage(
+	oldFiles *protoregistry.Files,
+	md protoreflect.MessageDescriptor,
+	seen map[protoreflect.FullName]bool,
+	cache map[protoreflect.FullName][]Change,
+	changes []Change,
+) []Change {
+	if seen[md.FullName()] {
+		return changes
+	}
+	seen[md.FullName()] = true
+
+	if cached, ok := cache[md.FullName()]; ok {
+		changes = append(changes, cached...)
+	} else if oldDesc, err := oldFiles.FindDescriptorByName(md.FullName()); err == nil {
+		if oldMD, ok := oldDesc.(protoreflect.MessageDescriptor); ok {
+			found := diffMessage(oldMD, md)
+			cache[md.FullName()] = found
+			changes = append(changes, found...)
+		}
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		switch {
+		case fd.Message() != nil:
+			changes = compareMessage(oldFiles, fd.Message(), seen, cache, changes)
+		case fd.Enum() != nil:
+			changes = compareEnum(oldFiles, fd.Enum(), seen, changes)
+		}
+	}
+	return changes
+}
+
+func compareEnum(
+	oldFiles *protoregistry.Files,
+	ed protoreflect.EnumDescriptor,
+	seen map[protoreflect.FullName]bool,
+	changes []Change,
+) []Change {
+	if seen[ed.FullName()] {
+		return changes
+	}
+	seen[ed.FullName()] = true
+	oldDesc, err := oldFiles.FindDescriptorByName(ed.FullName())
+	if err != nil {
+		return changes
+	}
+	oldED, ok := oldDesc.(protoreflect.EnumDescriptor)
+	if !ok {
+		return changes
+	}
+	values := ed.Values()
+	for i := 0; i < values.Len(); i++ {
+		v := values.Get(i)
+		if oldED.Values().ByNumber(v.Number()) == nil {
+			changes = append(changes, Change{
+				Kind:      EnumValueAdded,
+				Type:      string(ed.FullName()),
+				Name:      string(v.Name()),
+				NewNumber: int32(v.Number()),
+			})
+		}
+	}
+	return changes
+}
+
+func diffMessage(oldMD, newMD protoreflect.MessageDescriptor) []Change {
+	var changes []Change
+	typeName := string(newMD.FullName())
+	oldFields, newFields := oldMD.Fields(), newMD.Fields()
+	matched := map[protoreflect.FieldNumber]bool{}
+	for i := 0; i < newFields.Len(); i++ {
+		fd := newFields.Get(i)
+		// fields are the same on the wire when their numbers are, whatever their names
+		if oldFD := oldFields.ByNumber(fd.Number()); oldFD != nil {
+			matched[oldFD.Number()] = true
+			if oldFD.Name() != fd.Name() {
+				changes = append(changes, Change{
+					Kind:      FieldRenamed,
+					Type:      typeName,
+					Name:      string(fd.Name()),
+					OldName:   string(oldFD.Name()),
+					OldNumber: int32(oldFD.Number()),
+					NewNumber: int32(fd.Number()),
+				})
+			}
+			if !wireCompatible(oldFD, fd) {
+				changes = append(changes, Change{
+					Kind:      FieldTypeChanged,
+					Type:      typeName,
+					Name:      string(fd.Name()),
+					OldNumber: int32(oldFD.Number()),
+					NewNumber: int32(fd.Number()),
+					OldType:   fieldTypeName(oldFD),
+					NewType:   fieldTypeName(fd),
+				})
+			}
+			continue
+		}
+		// a field moved to a number that is new, from one that isn't reused
+		if oldFD := oldFields.ByName(fd.Name()); oldFD != nil && newFields.ByNumber(oldFD.Number()) == nil {
+			matched[oldFD.Number()] = true
+			changes = append(changes, Change{
+				Kind:      FieldRenumbered,
+				Type:      typeName,
+				Name:      string(fd.Name()),
+				OldNumber: int32(oldFD.Number()),
+				NewNumber: int32(fd.Number()),
+			})
+			continue
+		}
+		changes = append(changes, Change{
+			Kind:      FieldAdded,
+			Type:      typeName,
+			Name:      string(fd.Name()),
+			NewNumber: int32(fd.Number()),
+			NewType:   fieldTypeName(fd),
+		})
+	}
+	for i := 0; i < oldFields.Len(); i++ {
+		oldFD := oldFields.Get(i)
+		if !matched[oldFD.Number()] {
+			changes = append(changes, Change{
+				Kind:      FieldRemoved,
+				Type:      typeName,
+				Name:      string(oldFD.Name()),
+				OldNumber: int32(oldFD.Number()),
+				OldType:   fieldTypeName(oldFD),
+			})
+		}
+	}
+	return changes
+}
+
+// wireClass groups field kinds that can be exchanged for each other without breaking parsing, see
+// https://protobuf.dev/programming-guides/proto3/#updating.
+func wireClass(kind protoreflect.Kind) string {
+	switch kind {
+	case protoreflect.Int32Kind, protoreflect.Uint32Kind, protoreflect.Int64Kind, protoreflect.Uint64Kind,
+		protoreflect.BoolKind, protoreflect.EnumKind:
+		return "varint"
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "zigzag"
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind:
+		return "fixed32"
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind:
+		return "fixed64"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "bytes"
+	default:
+		return kind.String()
+	}
+}
+
+func wireCompatible(oldFD, newFD protoreflect.FieldDescriptor) bool {
+	if oldFD.IsMap() || newFD.IsMap() {
+		return oldFD.IsMap() && newFD.IsMap() &&
+			wireCompatible(oldFD.MapKey(), newFD.MapKey()) &&
+			wireCompatible(oldFD.MapValue(), newFD.MapValue())
+	}
+	if wireClass(oldFD.Kind()) != wireClass(newFD.Kind()) {
+		return false
+	}
+	// length-delimited values can move between singular and repeated, scalars can't because of
+	// packed encoding
+	if oldFD.IsList() != newFD.IsList() {
+		return wireClass(newFD.Kind()) == "bytes" || newFD.Kind() == protoreflect.MessageKind
+	}
+	return true
+}
+
+func fieldTypeName(fd protoreflect.FieldDescriptor) string {
+	if fd.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldTypeName(fd.MapKey()), fieldTypeName(fd.MapValue()))
+	}
+	var name string
+	switch {
+	case fd.Message() != nil:
+		name = string(fd.Message().FullName())
+	case fd.Enum() != nil:
+		name = string(fd.Enum().FullName())
+	default:
+		name = fd.Kind().String()
+	}
+	if fd.IsList() {
+		return "repeated " + name
+	}
+	return name
+}
+
+// WriteText writes the report as plain text, one line per change.
+func (r *CompatReport) WriteText(w io.Writer) error {
+	for _, m := range r.Methods {
+		if _, err := fmt.Fprintln(w, m.Procedure); err != nil {
+			return err
+		}
+		for _, c := range m.Changes {
+			if _, err := fmt.Fprintf(w, "  %s: %s\n", c.Type, c.describe()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *CompatReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes the report as a Markdown table, suitable for pull request comments.
+func (r *CompatReport) WriteMarkdown(w io.Writer) error {
+	if r.Empty() {
+		_, err := fmt.Fprintln(w, "No changes found.")
+		return err
+	}
+	lines := []string{
+		"| Method | Type | Change |",
+		"| --- | --- | --- |",
+	}
+	for _, m := range r.Methods {
+		for _, c := range m.Changes {
+			lines = append(lines, fmt.Sprintf("| `%s` | `%s` | %s |", m.Procedure, c.Type, markdownEscape(c.describe())))
+		}
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func (c Change) describe() string {
+	switch c.Kind {
+	case FieldAdded:
+		return fmt.Sprintf("field added: %s = %d (%s)", c.Name, c.NewNumber, c.NewType)
+	case FieldRemoved:
+		return fmt.Sprintf("field removed: %s = %d (%s)", c.Name, c.OldNumber, c.OldType)
+	case FieldRenumbered:
+		return fmt.Sprintf("field renumbered: %s = %d -> %d", c.Name, c.OldNumber, c.NewNumber)
+	case FieldRenamed:
+		return fmt.Sprintf("field renamed: %s -> %s = %d", c.OldName, c.Name, c.NewNumber)
+	case FieldTypeChanged:
+		return fmt.Sprintf("field type changed: %s = %d (%s -> %s)", c.Name, c.NewNumber, c.OldType, c.NewType)
+	case EnumValueAdded:
+		return fmt.Sprintf("enum value added: %s = %d", c.Name, c.NewNumber)
+	default:
+		return fmt.Sprintf("%s: %s", c.Kind, c.Name)
+	}
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;").Replace(s)
+}
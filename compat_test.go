@@ -0,0 +1,99 @@
+package unknownconnect_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCompareDescriptorSets(t *testing.T) {
+	newFile := protodesc.ToFileDescriptorProto(new.File_internal_proto_new_user_proto)
+	oldFile := proto.Clone(newFile).(*descriptorpb.FileDescriptorProto)
+	for _, msg := range oldFile.MessageType {
+		switch msg.GetName() {
+		case "NewUserRequest":
+			// drop primative_map and msg_map, make primative_list singular
+			msg.Field = []*descriptorpb.FieldDescriptorProto{msg.Field[0], msg.Field[3], msg.Field[4]}
+			msg.Field[1].Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+			msg.NestedType = nil
+		case "User":
+			msg.Field[1].Number = proto.Int32(3)
+			msg.Field = append(msg.Field, &descriptorpb.FieldDescriptorProto{
+				Name:     proto.String("age"),
+				Number:   proto.Int32(4),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+				JsonName: proto.String("age"),
+			})
+		}
+	}
+
+	report, err := unknownconnect.CompareDescriptorSets(
+		&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{oldFile}},
+		&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{newFile}},
+	)
+	require.NoError(t, err)
+	require.Len(t, report.Methods, 1)
+	assert.Equal(t, "/helloworld.new.UserManagement/NewUser", report.Methods[0].Procedure)
+	assert.Equal(t, []unknownconnect.Change{
+		{Kind: unknownconnect.FieldAdded, Type: "helloworld.new.NewUserRequest", Name: "primative_map", NewNumber: 2, NewType: "map<int32, int32>"},
+		{Kind: unknownconnect.FieldAdded, Type: "helloworld.new.NewUserRequest", Name: "msg_map", NewNumber: 3, NewType: "map<int32, helloworld.new.User>"},
+		{Kind: unknownconnect.FieldTypeChanged, Type: "helloworld.new.NewUserRequest", Name: "primative_list", OldNumber: 4, NewNumber: 4, OldType: "int32", NewType: "repeated int32"},
+		{Kind: unknownconnect.FieldRenumbered, Type: "helloworld.new.User", Name: "email", OldNumber: 3, NewNumber: 2},
+		{Kind: unknownconnect.FieldRemoved, Type: "helloworld.new.User", Name: "age", OldNumber: 4, OldType: "int32"},
+	}, report.Methods[0].Changes)
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteText(&buf))
+		assert.Contains(t, buf.String(), "/helloworld.new.UserManagement/NewUser\n")
+		assert.Contains(t, buf.String(), "  helloworld.new.User: field renumbered: email = 3 -> 2\n")
+	})
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteJSON(&buf))
+		decoded := &unknownconnect.CompatReport{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), decoded))
+		assert.Equal(t, report, decoded)
+	})
+	t.Run("markdown", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, report.WriteMarkdown(&buf))
+		assert.Contains(t, buf.String(), "| Method | Type | Change |\n")
+		assert.Contains(t, buf.String(), "| `/helloworld.new.UserManagement/NewUser` | `helloworld.new.NewUserRequest` | field added: msg_map = 3 (map&lt;int32, helloworld.new.User&gt;) |\n")
+	})
+	t.Run("reused number", func(t *testing.T) {
+		oldFile := proto.Clone(newFile).(*descriptorpb.FileDescriptorProto)
+		for _, msg := range oldFile.MessageType {
+			if msg.GetName() == "User" {
+				msg.Field[1].Name = proto.String("age")
+				msg.Field[1].JsonName = proto.String("age")
+				msg.Field[1].Type = descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()
+			}
+		}
+		report, err := unknownconnect.CompareDescriptorSets(
+			&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{oldFile}},
+			&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{newFile}},
+		)
+		require.NoError(t, err)
+		require.Len(t, report.Methods, 1)
+		assert.Equal(t, []unknownconnect.Change{
+			{Kind: unknownconnect.FieldRenamed, Type: "helloworld.new.User", Name: "email", OldName: "age", OldNumber: 2, NewNumber: 2},
+			{Kind: unknownconnect.FieldTypeChanged, Type: "helloworld.new.User", Name: "email", OldNumber: 2, NewNumber: 2, OldType: "int32", NewType: "string"},
+		}, report.Methods[0].Changes)
+	})
+	t.Run("same schema", func(t *testing.T) {
+		set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{newFile}}
+		report, err := unknownconnect.CompareDescriptorSets(set, set)
+		require.NoError(t, err)
+		assert.True(t, report.Empty())
+	})
+}
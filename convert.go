@@ -0,0 +1,205 @@
+package unknownconnect
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DescriptorResolver looks up descriptors by their full name. *protoregistry.Files implements it.
+type DescriptorResolver interface {
+	FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+}
+
+// AsMap converts the unknown fields of the report into a map that can be encoded as JSON, which
+// protojson can't do as it drops unknown fields. The map has an entry for every message with
+// unknown fields, keyed by its path ("." for the scanned message), holding its fields.
+//
+// If resolver is not nil, it is asked for a newer version of the type of each message holding
+// unknown fields. Fields known to the newer version are decoded as protojson would, keyed by their
+// JSON name. Other fields are keyed by their number and decoded heuristically: length-delimited
+// values become strings, nested maps or bytes, which encode as base64, and groups become nested
+// maps. Like FormatRaw, messages nested more than 32 levels deep are kept as bytes. Repeated fields
+// become lists.
+// Unknown JSON keys are kept with their key and value, and malformed bytes are found under "0".
+// Redacted fields become an object with their length and HMAC.
+func (r *Report) AsMap(resolver DescriptorResolver) map[string]any {
+	out := map[string]any{}
+	type pendingFields struct {
+		md  protoreflect.MessageDescriptor
+		raw []byte
+	}
+	pending := map[string]*pendingFields{}
+	for _, f := range r.Fields {
+		p := f.Path
+		if p == "" {
+			p = "."
+		}
+		fields, ok := out[p].(map[string]any)
+		if !ok {
+			fields = map[string]any{}
+			out[p] = fields
+		}
+		switch {
+		case f.HMAC != nil:
+			key := f.Name
+			if key == "" {
+				key = strconv.Itoa(int(f.Number))
+			}
+			addValue(fields, key, map[string]any{"length": f.Length, "hmac": hex.EncodeToString(f.HMAC)})
+		case f.Name != "":
+			var v any
+			if err := json.Unmarshal(f.Raw, &v); err != nil {
+				v = string(f.Raw)
+			}
+			addValue(fields, f.Name, v)
+		case f.Number == 0:
+			addValue(fields, "0", f.Raw)
+		default:
+			if md := newerDescriptor(resolver, f.Parent); md != nil && md.Fields().ByNumber(f.Number) != nil {
+				if pending[p] == nil {
+					pending[p] = &pendingFields{md: md}
+				}
+				pending[p].raw = append(pending[p].raw, f.Raw...)
+				continue
+			}
+			decodeRawFields(fields, f.Raw, 0)
+		}
+	}
+	for p, pf := range pending {
+		decodeNewerFields(out[p].(map[string]any), pf.md, pf.raw)
+	}
+	return out
+}
+
+// AsStruct converts the unknown fields of the report like AsMap, as a google.protobuf.Struct.
+func (r *Report) AsStruct(resolver DescriptorResolver) (*structpb.Struct, error) {
+	return structpb.NewStruct(r.AsMap(resolver))
+}
+
+// Upcast moves all data of msg, including its unknown fields, into into, which is usually a newer
+// version of the same message type: either a generated type or a dynamicpb.Message created from a
+// newer descriptor. Fields known to the newer type are parsed into it, and the returned report lists
+// the fields that are still unknown. msg is not modified and into is reset first.
+func Upcast(msg, into proto.Message) (*Report, error) {
+	b, err := proto.MarshalOptions{AllowPartial: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	err = proto.UnmarshalOptions{AllowPartial: true}.Unmarshal(b, into)
+	if err != nil {
+		return nil, err
+	}
+	return NewReport(into.ProtoReflect()), nil
+}
+
+func newerDescriptor(resolver DescriptorResolver, parent protoreflect.Message) protoreflect.MessageDescriptor {
+	if resolver == nil || parent == nil {
+		return nil
+	}
+	d, err := resolver.FindDescriptorByName(parent.Descriptor().FullName())
+	if err != nil {
+		return nil
+	}
+	md, _ := d.(protoreflect.MessageDescriptor)
+	return md
+}
+
+// decodeNewerFields decodes fields known to a newer message type with protojson. Anything the newer
+// type can't parse either is decoded heuristically.
+func decodeNewerFields(fields map[string]any, md protoreflect.MessageDescriptor, raw []byte) {
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(raw, msg); err != nil {
+		decodeRawFields(fields, raw, 0)
+		return
+	}
+	var decoded map[string]any
+	b, err := protojson.Marshal(msg)
+	if err == nil {
+		err = json.Unmarshal(b, &decoded)
+	}
+	if err != nil {
+		decodeRawFields(fields, raw, 0)
+		return
+	}
+	for k, v := range decoded {
+		fields[k] = v
+	}
+	decodeRawFields(fields, msg.GetUnknown(), 0)
+}
+
+// decodeRawFields adds every field of b to fields, keyed by field number. Messages nested deeper
+// than maxRawDepth are kept as bytes, like FormatRaw does.
+func decodeRawFields(fields map[string]any, b []byte, depth int) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			addValue(fields, "0", b)
+			return
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			addValue(fields, "0", b)
+			return
+		}
+		addValue(fields, strconv.Itoa(int(num)), decodeRawValue(num, typ, b[n:n+m], depth))
+		b = b[n+m:]
+	}
+}
+
+func decodeRawValue(num protowire.Number, typ protowire.Type, b []byte, depth int) any {
+	switch typ {
+	case protowire.VarintType:
+		v, _ := protowire.ConsumeVarint(b)
+		return v
+	case protowire.Fixed32Type:
+		v, _ := protowire.ConsumeFixed32(b)
+		return v
+	case protowire.Fixed64Type:
+		v, _ := protowire.ConsumeFixed64(b)
+		return v
+	case protowire.BytesType:
+		v, _ := protowire.ConsumeBytes(b)
+		switch {
+		case isPrintable(v):
+			return string(v)
+		case depth < maxRawDepth && isMessage(v):
+			nested := map[string]any{}
+			decodeRawFields(nested, v, depth+1)
+			return nested
+		default:
+			return v
+		}
+	case protowire.StartGroupType:
+		v, _ := protowire.ConsumeGroup(num, b)
+		if depth >= maxRawDepth {
+			return v
+		}
+		nested := map[string]any{}
+		decodeRawFields(nested, v, depth+1)
+		return nested
+	default:
+		return b
+	}
+}
+
+// addValue sets fields[key] to v, turning the value into a list if the key is repeated.
+func addValue(fields map[string]any, key string, v any) {
+	existing, ok := fields[key]
+	if !ok {
+		fields[key] = v
+		return
+	}
+	if list, ok := existing.([]any); ok {
+		fields[key] = append(list, v)
+		return
+	}
+	fields[key] = []any{existing, v}
+}
@@ -0,0 +1,168 @@
+package unknownconnect_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/testing/protopack"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestReportAsMap(t *testing.T) {
+	b, err := proto.Marshal(&new.NewUserRequest{
+		User:    &new.User{Name: "bob", Email: "bob@example.com"},
+		MsgList: []*new.User{{Name: "alice"}},
+	})
+	require.NoError(t, err)
+	b = append(b, protopack.Message{
+		protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1),
+		protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(2),
+		protopack.Tag{Number: 301, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+			protopack.Tag{Number: 1, Type: protopack.Fixed32Type}, protopack.Uint32(7),
+		}},
+	}.Marshal()...)
+	msg := &old.NewUserRequest{}
+	require.NoError(t, proto.Unmarshal(b, msg))
+	report := unknownconnect.NewReport(msg.ProtoReflect())
+
+	t.Run("heuristic", func(t *testing.T) {
+		assert.Equal(t, map[string]any{
+			".": map[string]any{
+				"5":   map[string]any{"1": "alice"},
+				"300": []any{uint64(1), uint64(2)},
+				"301": map[string]any{"1": uint32(7)},
+			},
+			"user": map[string]any{"2": "bob@example.com"},
+		}, report.AsMap(nil))
+	})
+
+	t.Run("with newer descriptor", func(t *testing.T) {
+		m := report.AsMap(newerOldFiles(t))
+		assert.Equal(t, map[string]any{
+			".": map[string]any{
+				"msgList": []any{map[string]any{"name": "alice"}},
+				"300":     []any{uint64(1), uint64(2)},
+				"301":     map[string]any{"1": uint32(7)},
+			},
+			"user": map[string]any{"email": "bob@example.com"},
+		}, m)
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		s, err := report.AsStruct(nil)
+		require.NoError(t, err)
+		b, err := protojson.Marshal(s)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{".": {"5": {"1": "alice"}, "300": [1, 2], "301": {"1": 7}}, "user": {"2": "bob@example.com"}}`, string(b))
+	})
+
+	t.Run("json keys", func(t *testing.T) {
+		report, err := unknownconnect.UnmarshalJSON([]byte(`{"user": {"name": "bob", "nickname": "bobby", "tags": ["a"]}}`), &old.NewUserRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"user": map[string]any{"nickname": "bobby", "tags": []any{"a"}},
+		}, report.AsMap(nil))
+	})
+
+	t.Run("deeply nested", func(t *testing.T) {
+		raw := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1)
+		for i := 0; i < 5000; i++ {
+			group := protowire.AppendTag(nil, 9, protowire.StartGroupType)
+			group = append(group, raw...)
+			raw = protowire.AppendTag(group, 9, protowire.EndGroupType)
+		}
+		user := &old.User{}
+		user.ProtoReflect().SetUnknown(raw)
+		m := unknownconnect.NewReport(user.ProtoReflect()).AsMap(nil)
+
+		// groups are nested maps down to the same depth as FormatRaw goes, and bytes below
+		v := m["."]
+		for depth := 0; depth <= 32; depth++ {
+			fields, ok := v.(map[string]any)
+			require.True(t, ok, "depth %d", depth)
+			v = fields["9"]
+		}
+		assert.IsType(t, []byte(nil), v)
+	})
+}
+
+// newerOldFiles returns the new schema, renamed to the package of the old one, so that it can be
+// used to decode unknown fields of the old types.
+func newerOldFiles(t *testing.T) *protoregistry.Files {
+	t.Helper()
+	fdp := protodesc.ToFileDescriptorProto(new.File_internal_proto_new_user_proto)
+	fdp.Name = proto.String("newer/user.proto")
+	fdp.Package = proto.String("helloworld.old")
+	for _, m := range fdp.MessageType {
+		for _, f := range m.Field {
+			if f.TypeName != nil {
+				f.TypeName = proto.String(strings.Replace(f.GetTypeName(), ".helloworld.new.", ".helloworld.old.", 1))
+			}
+		}
+		for _, nested := range m.NestedType {
+			for _, f := range nested.Field {
+				if f.TypeName != nil {
+					f.TypeName = proto.String(strings.Replace(f.GetTypeName(), ".helloworld.new.", ".helloworld.old.", 1))
+				}
+			}
+		}
+	}
+	for _, s := range fdp.Service {
+		for _, m := range s.Method {
+			m.InputType = proto.String(strings.Replace(m.GetInputType(), ".helloworld.new.", ".helloworld.old.", 1))
+			m.OutputType = proto.String(strings.Replace(m.GetOutputType(), ".helloworld.new.", ".helloworld.old.", 1))
+		}
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	require.NoError(t, err)
+	files := &protoregistry.Files{}
+	require.NoError(t, files.RegisterFile(fd))
+	return files
+}
+
+func TestUpcast(t *testing.T) {
+	b, err := proto.Marshal(&new.NewUserRequest{
+		User:         &new.User{Name: "bob", Email: "bob@example.com"},
+		PrimativeMap: map[int32]int32{1: 2},
+	})
+	require.NoError(t, err)
+	b = append(b, protopack.Message{
+		protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1),
+	}.Marshal()...)
+	msg := &old.NewUserRequest{}
+	require.NoError(t, proto.Unmarshal(b, msg))
+
+	t.Run("generated", func(t *testing.T) {
+		upcast := &new.NewUserRequest{}
+		report, err := unknownconnect.Upcast(msg, upcast)
+		require.NoError(t, err)
+		assert.Equal(t, "bob@example.com", upcast.GetUser().GetEmail())
+		assert.Equal(t, map[int32]int32{1: 2}, upcast.GetPrimativeMap())
+		require.Len(t, report.Fields, 1)
+		assert.Equal(t, protowire.Number(300), report.Fields[0].Number)
+		assert.True(t, unknownconnect.MessageHasUnknownFields(msg.ProtoReflect()), "the original message is left alone")
+	})
+
+	t.Run("dynamic", func(t *testing.T) {
+		d, err := newerOldFiles(t).FindDescriptorByName("helloworld.old.NewUserRequest")
+		require.NoError(t, err)
+		upcast := dynamicpb.NewMessage(d.(protoreflect.MessageDescriptor))
+		report, err := unknownconnect.Upcast(msg, upcast)
+		require.NoError(t, err)
+		user := upcast.Get(upcast.Descriptor().Fields().ByName("user")).Message()
+		assert.Equal(t, "bob@example.com", user.Get(user.Descriptor().Fields().ByName("email")).String())
+		require.Len(t, report.Fields, 1)
+		assert.Equal(t, protowire.Number(300), report.Fields[0].Number)
+	})
+}
@@ -0,0 +1,115 @@
+package unknownconnect
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+// DelimitedReport is a Report for a single record of a size-delimited stream.
+type DelimitedReport struct {
+	Report
+	// Record is the zero-based index of the record in the stream.
+	Record int
+	// Offset is the byte offset of the start of the record, including its size prefix.
+	Offset int64
+}
+
+// DelimitedReader reads size-delimited messages, as written by protodelim, and inspects each one
+// for unknown fields.
+type DelimitedReader struct {
+	r      *countingReader
+	opts   *interceptorOpts
+	record int
+	usage  streamUsage
+}
+
+// NewDelimitedReader creates a DelimitedReader reading from r. WithDrop removes unknown fields
+// from each message after it has been inspected. Callbacks registered with WithCallback are called
+// with context.Background() and a zero connect.Spec, and any error they return is returned by Read.
+// The per-stream limits of WithUnknownLimits apply to the whole stream of records, and records left
+// out by WithSampling aren't reported.
+func NewDelimitedReader(r io.Reader, opts ...option) *DelimitedReader {
+	return &DelimitedReader{
+		r:    &countingReader{r: bufio.NewReader(r)},
+		opts: newInterceptorOpts(opts),
+	}
+}
+
+// Read reads the next record into msg. It returns a report if the record has unknown fields and
+// nil otherwise. io.EOF is returned when there are no more records.
+func (d *DelimitedReader) Read(msg proto.Message) (*DelimitedReport, error) {
+	offset := d.r.n
+	if err := protodelim.UnmarshalFrom(d.r, msg); err != nil {
+		return nil, err
+	}
+	record := d.record
+	d.record++
+
+	// the report is built once, after the extensions are resolved, and is the one callbacks get
+	report, err := inspectMessage(context.Background(), msg, connect.Spec{}, noHeader, d.opts, &d.usage, true)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil || (report.Empty() && !report.Truncated) {
+		return nil, nil
+	}
+	return &DelimitedReport{Report: *report, Record: record, Offset: offset}, nil
+}
+
+// DelimitedWriter writes size-delimited messages, as read by protodelim, refusing to persist
+// unknown fields.
+type DelimitedWriter struct {
+	w    io.Writer
+	opts *interceptorOpts
+}
+
+// NewDelimitedWriter creates a DelimitedWriter writing to w. By default, messages with unknown
+// fields are refused. With WithDrop, they are written without their unknown fields instead, and with
+// WithDropRules without the unknown fields selected by the rules.
+func NewDelimitedWriter(w io.Writer, opts ...option) *DelimitedWriter {
+	return &DelimitedWriter{w: w, opts: newInterceptorOpts(opts)}
+}
+
+// Write writes msg as a single record and returns the number of bytes written. If msg has unknown
+// fields and WithDrop wasn't given, nothing is written and an *UnknownFieldsError is returned. The
+// given message is never modified.
+func (d *DelimitedWriter) Write(msg proto.Message) (int, error) {
+	if report := NewReport(msg.ProtoReflect()); !report.Empty() {
+		if !d.opts.drop {
+			return 0, &UnknownFieldsError{Report: report}
+		}
+		msg = proto.Clone(msg)
+		dropUnknownFields(msg.ProtoReflect(), d.opts.dropRules)
+	}
+	return protodelim.MarshalTo(d.w, msg)
+}
+
+func noHeader() http.Header {
+	return nil
+}
+
+// countingReader keeps track of the offset into the stream for reporting.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
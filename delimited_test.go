@@ -0,0 +1,117 @@
+package unknownconnect_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDelimitedReader(t *testing.T) {
+	var buf bytes.Buffer
+	var offsets []int64
+	for _, user := range []*new.User{
+		{Name: "alice"},
+		{Name: "bob", Email: "bob@example.com"},
+		{Name: "carol", Email: "carol@example.com"},
+	} {
+		offsets = append(offsets, int64(buf.Len()))
+		_, err := protodelim.MarshalTo(&buf, user)
+		require.NoError(t, err)
+	}
+
+	t.Run("reports unknown fields", func(t *testing.T) {
+		reader := unknownconnect.NewDelimitedReader(bytes.NewReader(buf.Bytes()))
+		var reports []*unknownconnect.DelimitedReport
+		for {
+			user := &old.User{}
+			report, err := reader.Read(user)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			if report != nil {
+				assert.NotEmpty(t, user.ProtoReflect().GetUnknown())
+				reports = append(reports, report)
+			}
+		}
+		require.Len(t, reports, 2)
+		assert.Equal(t, 1, reports[0].Record)
+		assert.Equal(t, offsets[1], reports[0].Offset)
+		assert.Equal(t, 2, reports[1].Record)
+		assert.Equal(t, offsets[2], reports[1].Offset)
+		assert.Len(t, reports[1].Fields, 1)
+	})
+	t.Run("drop", func(t *testing.T) {
+		var called int
+		reader := unknownconnect.NewDelimitedReader(bytes.NewReader(buf.Bytes()),
+			unknownconnect.WithDrop(),
+			unknownconnect.WithCallback(func(context.Context, connect.Spec, proto.Message) error {
+				called++
+				return nil
+			}))
+		for i := 0; i < 3; i++ {
+			user := &old.User{}
+			_, err := reader.Read(user)
+			require.NoError(t, err)
+			assert.Empty(t, user.ProtoReflect().GetUnknown())
+		}
+		_, err := reader.Read(&old.User{})
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, 2, called)
+	})
+	t.Run("callback error", func(t *testing.T) {
+		reader := unknownconnect.NewDelimitedReader(bytes.NewReader(buf.Bytes()),
+			unknownconnect.WithCallback(func(context.Context, connect.Spec, proto.Message) error {
+				return errors.New("unknown fields error")
+			}))
+		_, err := reader.Read(&old.User{})
+		require.NoError(t, err)
+		_, err = reader.Read(&old.User{})
+		assert.ErrorContains(t, err, "unknown fields error")
+	})
+}
+
+func TestDelimitedWriter(t *testing.T) {
+	body, err := proto.Marshal(&new.User{Name: "bob", Email: "bob@example.com"})
+	require.NoError(t, err)
+	user := &old.User{}
+	require.NoError(t, proto.Unmarshal(body, user))
+
+	t.Run("refuse", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := unknownconnect.NewDelimitedWriter(&buf)
+		_, err := writer.Write(&old.User{Name: "alice"})
+		require.NoError(t, err)
+		written := buf.Len()
+
+		_, err = writer.Write(user)
+		var unknownErr *unknownconnect.UnknownFieldsError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Len(t, unknownErr.Report.Fields, 1)
+		assert.Equal(t, written, buf.Len())
+	})
+	t.Run("drop", func(t *testing.T) {
+		var buf bytes.Buffer
+		writer := unknownconnect.NewDelimitedWriter(&buf, unknownconnect.WithDrop())
+		n, err := writer.Write(user)
+		require.NoError(t, err)
+		assert.Equal(t, buf.Len(), n)
+		assert.NotEmpty(t, user.ProtoReflect().GetUnknown())
+
+		got := &new.User{}
+		require.NoError(t, protodelim.UnmarshalFrom(&buf, got))
+		assert.Equal(t, "bob", got.Name)
+		assert.Empty(t, got.Email)
+	})
+}
@@ -0,0 +1,220 @@
+package unknownconnect
+
+import (
+	"fmt"
+
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DeprecatedUsage is a deprecated field, or a field holding a deprecated enum value, found set in a
+// message. Peers still sending those are what keeps a field from being reserved and removed.
+type DeprecatedUsage struct {
+	// Path is the location of the message holding the field, like UnknownField.Path.
+	Path string
+	// Field is the field that is set. It is deprecated itself unless Value is set.
+	Field protoreflect.FieldDescriptor
+	// Value is the deprecated enum value the field holds, or one of them for lists and maps.
+	Value protoreflect.EnumValueDescriptor
+}
+
+// Name returns the full name of what is deprecated, the enum value if there is one or the field.
+func (u DeprecatedUsage) Name() protoreflect.FullName {
+	if u.Value != nil {
+		return u.Value.FullName()
+	}
+	return u.Field.FullName()
+}
+
+func (u DeprecatedUsage) String() string {
+	return fmt.Sprintf("%s: %s", wire.JoinPath(u.Path, string(u.Field.Name()), ""), u.Name())
+}
+
+// FindDeprecated returns every deprecated field and every deprecated enum value set in msg and its
+// nested messages. An enum value is listed once per field, even if a list or map holds it many times.
+func FindDeprecated(msg protoreflect.Message) []DeprecatedUsage {
+	usages, _ := findDeprecated(msg, ScanLimits{})
+	return usages
+}
+
+// findDeprecated is like FindDeprecated but stops when it hits the depth or node limit, and returns
+// true then. Nested messages whose type can't hold anything deprecated aren't visited, nor counted.
+func findDeprecated(msg protoreflect.Message, limits ScanLimits) ([]DeprecatedUsage, bool) {
+	f := &deprecationFinder{limits: limits}
+	f.find(msg, nil)
+	return f.usages, f.exceeded
+}
+
+// deprecationFinder holds the state of a single search for deprecated fields and values.
+type deprecationFinder struct {
+	limits   ScanLimits
+	nodes    int
+	exceeded bool
+	usages   []DeprecatedUsage
+}
+
+func (f *deprecationFinder) find(msg protoreflect.Message, p path) {
+	if f.exceeded {
+		return
+	}
+	f.nodes++
+	if (f.limits.MaxDepth > 0 && len(p) > f.limits.MaxDepth) || (f.limits.MaxNodes > 0 && f.nodes > f.limits.MaxNodes) {
+		f.exceeded = true
+		return
+	}
+	plan := deprecationPlanFor(msg.Descriptor())
+	for _, fd := range plan.fields {
+		if msg.Has(fd) {
+			f.usages = append(f.usages, DeprecatedUsage{Path: p.String(), Field: fd})
+		}
+	}
+	for _, fd := range plan.enums {
+		if !msg.Has(fd) {
+			continue
+		}
+		for _, ev := range deprecatedValues(fd, msg.Get(fd)) {
+			f.usages = append(f.usages, DeprecatedUsage{Path: p.String(), Field: fd, Value: ev})
+		}
+	}
+	for _, fd := range plan.nested {
+		if !msg.Has(fd) {
+			continue
+		}
+		v := msg.Get(fd)
+		switch {
+		case fd.IsMap():
+			v.Map().Range(func(mk protoreflect.MapKey, mv protoreflect.Value) bool {
+				f.find(mv.Message(), p.push(fd, wire.MapKey(mk)))
+				return !f.exceeded
+			})
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len() && !f.exceeded; i++ {
+				f.find(list.Get(i).Message(), p.push(fd, fmt.Sprint(i)))
+			}
+		default:
+			f.find(v.Message(), p.push(fd, ""))
+		}
+	}
+}
+
+// deprecatedValues returns the deprecated enum values held by an enum field, a list of enums or a
+// map with enum values, in the order they are first seen.
+func deprecatedValues(fd protoreflect.FieldDescriptor, v protoreflect.Value) []protoreflect.EnumValueDescriptor {
+	var found []protoreflect.EnumValueDescriptor
+	seen := map[protoreflect.EnumNumber]bool{}
+	add := func(ed protoreflect.EnumDescriptor, num protoreflect.EnumNumber) {
+		if seen[num] {
+			return
+		}
+		seen[num] = true
+		if ev := ed.Values().ByNumber(num); ev != nil && isDeprecated(ev) {
+			found = append(found, ev)
+		}
+	}
+	switch {
+	case fd.IsMap():
+		ed := fd.MapValue().Enum()
+		v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+			add(ed, mv.Enum())
+			return true
+		})
+	case fd.IsList():
+		for i := 0; i < v.List().Len(); i++ {
+			add(fd.Enum(), v.List().Get(i).Enum())
+		}
+	default:
+		add(fd.Enum(), v.Enum())
+	}
+	return found
+}
+
+// deprecationPlan lists the fields of a message type that are deprecated, the enum fields whose
+// enum has deprecated values, and the message fields whose type can hold either, directly or in
+// its own nested messages.
+type deprecationPlan struct {
+	fields []protoreflect.FieldDescriptor
+	enums  []protoreflect.FieldDescriptor
+	nested []protoreflect.FieldDescriptor
+}
+
+// deprecationPlans caches a *deprecationPlan per protoreflect.MessageDescriptor.
+var deprecationPlans planCache[deprecationPlan]
+
+func deprecationPlanFor(md protoreflect.MessageDescriptor) *deprecationPlan {
+	return deprecationPlans.get(md, func(md protoreflect.MessageDescriptor) *deprecationPlan {
+		plan := &deprecationPlan{}
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			if isDeprecated(fd) {
+				plan.fields = append(plan.fields, fd)
+			}
+			ed := fd.Enum()
+			if fd.IsMap() {
+				ed = fd.MapValue().Enum()
+			}
+			if ed != nil && hasDeprecatedValues(ed) {
+				plan.enums = append(plan.enums, fd)
+			}
+		}
+		for _, fd := range walkPlanFor(md).fields {
+			nested := fd.Message()
+			if fd.IsMap() {
+				nested = fd.MapValue().Message()
+			}
+			if mayHoldDeprecated(nested, map[protoreflect.FullName]bool{}) {
+				plan.nested = append(plan.nested, fd)
+			}
+		}
+		return plan
+	})
+}
+
+// mayHoldDeprecated returns true if messages of type md can hold a deprecated field or enum value,
+// directly or in their nested messages. seen holds the types already looked at, for recursive ones.
+func mayHoldDeprecated(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) bool {
+	if seen[md.FullName()] {
+		return false
+	}
+	seen[md.FullName()] = true
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		switch {
+		case isDeprecated(fields.Get(i)):
+			return true
+		case fd.Enum() != nil && hasDeprecatedValues(fd.Enum()):
+			return true
+		case fd.Message() != nil && mayHoldDeprecated(fd.Message(), seen):
+			return true
+		}
+	}
+	return false
+}
+
+func hasDeprecatedValues(ed protoreflect.EnumDescriptor) bool {
+	values := ed.Values()
+	for i := 0; i < values.Len(); i++ {
+		if isDeprecated(values.Get(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeprecated returns true if the field or enum value has the deprecated option set.
+func isDeprecated(d protoreflect.Descriptor) bool {
+	switch opts := d.Options().(type) {
+	case *descriptorpb.FieldOptions:
+		return opts.GetDeprecated()
+	case *descriptorpb.EnumValueOptions:
+		return opts.GetDeprecated()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,114 @@
+package unknownconnect_test
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/account"
+	"google.golang.org/protobuf/proto"
+)
+
+// newAccount returns an account using every deprecated field and value once, directly and in a
+// linked account.
+func newAccount() *account.Account {
+	return &account.Account{
+		Name:     "bob",
+		LegacyId: "b-1",
+		Status:   account.Status_STATUS_ACTIVE,
+		History:  []account.Status{account.Status_STATUS_ACTIVE, account.Status_STATUS_SUSPENDED, account.Status_STATUS_SUSPENDED},
+		Linked: []*account.Account{{
+			Status:   account.Status_STATUS_SUSPENDED,
+			ByRegion: map[string]account.Status{"eu": account.Status_STATUS_SUSPENDED},
+		}},
+	}
+}
+
+func TestFindDeprecated(t *testing.T) {
+	var found []string
+	for _, u := range unknownconnect.FindDeprecated(newAccount().ProtoReflect()) {
+		found = append(found, u.String())
+	}
+	assert.ElementsMatch(t, []string{
+		"legacy_id: unknownconnect.account.Account.legacy_id",
+		"history: unknownconnect.account.STATUS_SUSPENDED",
+		"linked[0].status: unknownconnect.account.STATUS_SUSPENDED",
+		"linked[0].by_region: unknownconnect.account.STATUS_SUSPENDED",
+	}, found)
+
+	assert.Empty(t, unknownconnect.FindDeprecated((&account.Account{}).ProtoReflect()))
+}
+
+func TestInterceptorDeprecated(t *testing.T) {
+	var reports []*unknownconnect.Report
+	measurements := map[string]float64{}
+	interceptor := unknownconnect.NewInterceptor(
+		unknownconnect.WithDeprecated(),
+		unknownconnect.WithReportCallback(func(ctx context.Context, s connect.Spec, r *unknownconnect.Report) error {
+			reports = append(reports, r)
+			return nil
+		}),
+		unknownconnect.WithMetrics(unknownconnect.MetricsFunc(func(_ context.Context, m unknownconnect.Measurement) {
+			if m.Name == unknownconnect.MetricDeprecatedFields {
+				measurements[m.Label] += m.Value
+			}
+		})),
+	)
+	unary := interceptor.WrapUnary(connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		return nil, nil
+	}))
+
+	_, err := unary(context.Background(), connect.NewRequest(newAccount()))
+	require.NoError(t, err)
+	require.Len(t, reports, 1, "the report callback is called without unknown fields")
+	assert.Empty(t, reports[0].Fields)
+	assert.Len(t, reports[0].Deprecated, 4)
+	assert.Equal(t, map[string]float64{
+		"unknownconnect.account.Account.legacy_id": 1,
+		"unknownconnect.account.STATUS_SUSPENDED":  3,
+	}, measurements)
+
+	// an account without anything deprecated is not reported
+	_, err = unary(context.Background(), connect.NewRequest(&account.Account{}))
+	require.NoError(t, err)
+	assert.Len(t, reports, 1)
+}
+
+func TestInterceptorDeprecatedLimits(t *testing.T) {
+	intercept := func(t *testing.T, limits unknownconnect.ScanLimits, msg *account.Account) (*unknownconnect.Report, error) {
+		t.Helper()
+		var report *unknownconnect.Report
+		unary := unknownconnect.NewInterceptor(
+			unknownconnect.WithDeprecated(),
+			unknownconnect.WithScanLimits(limits),
+			unknownconnect.WithReportCallback(func(ctx context.Context, s connect.Spec, r *unknownconnect.Report) error {
+				report = r
+				return nil
+			}),
+		).WrapUnary(func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		})
+		_, err := unary(context.Background(), connect.NewRequest(msg))
+		return report, err
+	}
+	// the deprecated field is three linked accounts deep
+	deep := &account.Account{Linked: []*account.Account{{Linked: []*account.Account{{Linked: []*account.Account{{LegacyId: "b-1"}}}}}}}
+
+	t.Run("reject", func(t *testing.T) {
+		_, err := intercept(t, unknownconnect.ScanLimits{MaxDepth: 2, OnLimit: unknownconnect.LimitReject}, deep)
+		assert.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+		assert.ErrorIs(t, err, unknownconnect.ErrScanLimitExceeded)
+	})
+	t.Run("truncate", func(t *testing.T) {
+		deep := proto.Clone(deep).(*account.Account)
+		deep.LegacyId = "b-0"
+		report, err := intercept(t, unknownconnect.ScanLimits{MaxDepth: 2, OnLimit: unknownconnect.LimitTruncate}, deep)
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		assert.True(t, report.Truncated)
+		assert.Len(t, report.Deprecated, 1)
+	})
+}
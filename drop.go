@@ -0,0 +1,139 @@
+package unknownconnect
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DropRule selects unknown fields by where they are found. Every field set in a rule has to match,
+// and a zero rule matches every unknown field.
+type DropRule struct {
+	// Message matches unknown fields of messages with this full name, like "greet.v1.User".
+	Message protoreflect.FullName
+	// Path matches unknown fields of the message at this path and of every message nested in it.
+	// Paths are written like in UnknownField, with "." for the scanned message and "[*]" matching
+	// any list index or map key, like "users[*].address".
+	Path string
+	// MinNumber and MaxNumber match unknown fields with a number in this inclusive range. Zero
+	// leaves that end of the range open.
+	MinNumber, MaxNumber protowire.Number
+}
+
+// DropRules selects which unknown fields are dropped.
+type DropRules struct {
+	Rules []DropRule
+	// Except inverts the rules, so that every unknown field is dropped except those matched by one
+	// of the rules.
+	Except bool
+}
+
+// DropMatchingUnknownFields recursively drops the unknown fields of msg selected by rules and keeps
+// all others.
+func DropMatchingUnknownFields(msg protoreflect.Message, rules DropRules) {
+	dropUnknownFields(msg, &rules)
+}
+
+// dropUnknownFields is like DropUnknownFields, but if rules is not nil, only the unknown fields it
+// selects are dropped. It ignores ScanLimits: unknown fields past the limits would otherwise be
+// kept and passed on, which is what dropping is meant to prevent.
+func dropUnknownFields(msg protoreflect.Message, rules *DropRules) {
+	walkUnknownFields(msg, ScanLimits{}, func(p path, msg protoreflect.Message) bool {
+		if rules == nil {
+			msg.SetUnknown(nil)
+		} else {
+			msg.SetUnknown(rules.filterUnknown(p.String(), msg))
+		}
+		return true
+	})
+}
+
+func (r *DropRules) drops(p string, md protoreflect.MessageDescriptor, num protowire.Number) bool {
+	for _, rule := range r.Rules {
+		if rule.matches(p, md, num) {
+			return !r.Except
+		}
+	}
+	return r.Except
+}
+
+func (r DropRule) matches(p string, md protoreflect.MessageDescriptor, num protowire.Number) bool {
+	if r.Message != "" && r.Message != md.FullName() {
+		return false
+	}
+	if r.Path != "" && !pathHasPrefix(p, r.Path) {
+		return false
+	}
+	if r.MinNumber > 0 && num < r.MinNumber {
+		return false
+	}
+	if r.MaxNumber > 0 && num > r.MaxNumber {
+		return false
+	}
+	return true
+}
+
+// pathHasPrefix returns true if the path p is the path pattern or nested in it.
+func pathHasPrefix(p, pattern string) bool {
+	if pattern == "." {
+		return true
+	}
+	for pattern != "" {
+		if strings.HasPrefix(pattern, "[*]") {
+			if !strings.HasPrefix(p, "[") {
+				return false
+			}
+			end := strings.IndexByte(p, ']')
+			// quoted map keys can contain brackets
+			if strings.HasPrefix(p, `["`) {
+				end = closingQuote(p[1:]) + 2
+			}
+			if end < 1 || end >= len(p) || p[end] != ']' {
+				return false
+			}
+			p, pattern = p[end+1:], pattern[len("[*]"):]
+			continue
+		}
+		if p == "" || p[0] != pattern[0] {
+			return false
+		}
+		p, pattern = p[1:], pattern[1:]
+	}
+	return p == "" || p[0] == '.' || p[0] == '['
+}
+
+// closingQuote returns the index of the quote that ends the Go string literal at the start of s.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// filterUnknown returns the unknown fields of msg that rules doesn't drop. Bytes that can't be
+// parsed are treated as a field with number zero.
+func (r *DropRules) filterUnknown(p string, msg protoreflect.Message) protoreflect.RawFields {
+	b := msg.GetUnknown()
+	md := msg.Descriptor()
+	var kept protoreflect.RawFields
+	for len(b) > 0 {
+		num, _, n := protowire.ConsumeField(b)
+		if n < 0 {
+			if !r.drops(p, md, 0) {
+				kept = append(kept, b...)
+			}
+			break
+		}
+		if !r.drops(p, md, num) {
+			kept = append(kept, b[:n]...)
+		}
+		b = b[n:]
+	}
+	return kept
+}
@@ -0,0 +1,122 @@
+package unknownconnect_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+// dropTestMessage has unknown fields 300 and 301 in the request, in user and in both entries of
+// msg_list.
+func dropTestMessage() *new.NewUserRequest {
+	withUnknown := func(user *new.User) *new.User {
+		user.ProtoReflect().SetUnknown(protopack.Message{
+			protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1),
+			protopack.Tag{Number: 301, Type: protopack.VarintType}, protopack.Varint(2),
+		}.Marshal())
+		return user
+	}
+	req := &new.NewUserRequest{
+		User:    withUnknown(&new.User{Name: "bob"}),
+		MsgList: []*new.User{withUnknown(&new.User{Name: "alice"}), withUnknown(&new.User{Name: "eve"})},
+	}
+	req.ProtoReflect().SetUnknown(protopack.Message{
+		protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1),
+		protopack.Tag{Number: 301, Type: protopack.VarintType}, protopack.Varint(2),
+	}.Marshal())
+	return req
+}
+
+// remaining lists the unknown fields left in msg as "path#number".
+func remaining(msg proto.Message) []string {
+	var fields []string
+	for _, f := range unknownconnect.NewReport(msg.ProtoReflect()).Fields {
+		fields = append(fields, fmt.Sprintf("%s#%d", f.Path, f.Number))
+	}
+	return fields
+}
+
+func TestDropMatchingUnknownFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules unknownconnect.DropRules
+		want  []string
+	}{
+		{
+			name:  "no rules",
+			rules: unknownconnect.DropRules{},
+			want:  []string{"#300", "#301", "user#300", "user#301", "msg_list[0]#300", "msg_list[0]#301", "msg_list[1]#300", "msg_list[1]#301"},
+		},
+		{
+			name:  "message name",
+			rules: unknownconnect.DropRules{Rules: []unknownconnect.DropRule{{Message: "helloworld.new.User"}}},
+			want:  []string{"#300", "#301"},
+		},
+		{
+			name:  "path",
+			rules: unknownconnect.DropRules{Rules: []unknownconnect.DropRule{{Path: "user"}}},
+			want:  []string{"#300", "#301", "msg_list[0]#300", "msg_list[0]#301", "msg_list[1]#300", "msg_list[1]#301"},
+		},
+		{
+			name:  "path with wildcard",
+			rules: unknownconnect.DropRules{Rules: []unknownconnect.DropRule{{Path: "msg_list[*]"}}},
+			want:  []string{"#300", "#301", "user#300", "user#301"},
+		},
+		{
+			name:  "path with index",
+			rules: unknownconnect.DropRules{Rules: []unknownconnect.DropRule{{Path: "msg_list[1]"}}},
+			want:  []string{"#300", "#301", "user#300", "user#301", "msg_list[0]#300", "msg_list[0]#301"},
+		},
+		{
+			name:  "root path",
+			rules: unknownconnect.DropRules{Rules: []unknownconnect.DropRule{{Path: "."}}},
+			want:  nil,
+		},
+		{
+			name:  "number range",
+			rules: unknownconnect.DropRules{Rules: []unknownconnect.DropRule{{MinNumber: 301}}},
+			want:  []string{"#300", "user#300", "msg_list[0]#300", "msg_list[1]#300"},
+		},
+		{
+			name: "except",
+			rules: unknownconnect.DropRules{
+				Rules:  []unknownconnect.DropRule{{Path: "user", MaxNumber: 300}},
+				Except: true,
+			},
+			want: []string{"user#300"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := dropTestMessage()
+			unknownconnect.DropMatchingUnknownFields(msg.ProtoReflect(), tt.rules)
+			assert.Equal(t, tt.want, remaining(msg))
+		})
+	}
+}
+
+func TestInterceptorDropRules(t *testing.T) {
+	var reported int
+	interceptor := unknownconnect.NewInterceptor(
+		unknownconnect.WithReportCallback(func(ctx context.Context, s connect.Spec, r *unknownconnect.Report) error {
+			reported = len(r.Fields)
+			return nil
+		}),
+		unknownconnect.WithDropRules(unknownconnect.DropRules{Rules: []unknownconnect.DropRule{{Path: "msg_list[*]"}}}),
+	)
+	unary := interceptor.WrapUnary(connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		assert.Equal(t, []string{"#300", "#301", "user#300", "user#301"}, remaining(req.Any().(proto.Message)))
+		return nil, nil
+	}))
+	_, err := unary(context.Background(), connect.NewRequest(dropTestMessage()))
+	require.NoError(t, err)
+	assert.Equal(t, 8, reported, "every unknown field is reported before dropping")
+}
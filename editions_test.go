@@ -0,0 +1,196 @@
+package unknownconnect_test
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/legacy"
+	"github.com/sudorandom/unknownconnect-go/internal/protobuild"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protopack"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// editionsDescriptor describes a message of an editions file with delimited messages, closed enums
+// by default and an open enum. protodesc only accepts delimited fields declared like groups, next to
+// their message and named after it:
+//
+//	edition = "2023";
+//	option features.enum_type = CLOSED;
+//	message Shape {
+//	  string name = 1;
+//	  message Detail { string note = 1; }
+//	  Detail detail = 2 [features.message_encoding = DELIMITED];
+//	  Color color = 3;
+//	  Shade shade = 4;
+//	  message Part { string note = 1; }
+//	  repeated Part part = 5 [features.message_encoding = DELIMITED];
+//	}
+//	enum Color { COLOR_UNSPECIFIED = 0; COLOR_ONE = 1; }
+//	enum Shade { option features.enum_type = OPEN; SHADE_UNSPECIFIED = 0; SHADE_ONE = 1; }
+func editionsDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	delimited := &descriptorpb.FieldOptions{Features: &descriptorpb.FeatureSet{MessageEncoding: descriptorpb.FeatureSet_DELIMITED.Enum()}}
+	shape := protobuild.Message("Shape",
+		protobuild.Field("name", 1, protobuild.TypeString, ""),
+		protobuild.Field("detail", 2, protobuild.TypeMessage, "Detail").WithOptions(delimited),
+		protobuild.Field("color", 3, protobuild.TypeEnum, ".shapes.Color"),
+		protobuild.Field("shade", 4, protobuild.TypeEnum, ".shapes.Shade"),
+		protobuild.Field("part", 5, protobuild.TypeMessage, "Part").WithOptions(delimited).List(),
+	)
+	shape.NestedType = []*descriptorpb.DescriptorProto{
+		protobuild.Message("Detail", protobuild.Field("note", 1, protobuild.TypeString, "")),
+		protobuild.Message("Part", protobuild.Field("note", 1, protobuild.TypeString, "")),
+	}
+	shade := protobuild.Enum("Shade", "SHADE_UNSPECIFIED", "SHADE_ONE")
+	shade.Options = &descriptorpb.EnumOptions{Features: &descriptorpb.FeatureSet{EnumType: descriptorpb.FeatureSet_OPEN.Enum()}}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("shapes.proto"),
+		Package:     proto.String("shapes"),
+		Syntax:      proto.String("editions"),
+		Edition:     descriptorpb.Edition_EDITION_2023.Enum(),
+		Options:     &descriptorpb.FileOptions{Features: &descriptorpb.FeatureSet{EnumType: descriptorpb.FeatureSet_CLOSED.Enum()}},
+		EnumType:    []*descriptorpb.EnumDescriptorProto{protobuild.Enum("Color", "COLOR_UNSPECIFIED", "COLOR_ONE"), shade},
+		MessageType: []*descriptorpb.DescriptorProto{shape},
+	}, nil)
+	require.NoError(t, err)
+	return fd.Messages().ByName("Shape")
+}
+
+// addUnknown appends fields to the unknown fields of msg. The Go runtime doesn't move the values
+// closed enums don't define to the unknown fields while unmarshalling, unlike other runtimes, so
+// tests of enum drift put them there by hand.
+func addUnknown(msg protoreflect.Message, fields protopack.Message) {
+	msg.SetUnknown(append(msg.GetUnknown(), fields.Marshal()...))
+}
+
+// requireDropped drops the unknown fields of msg and checks that none are left, even after a round
+// trip through the wire format.
+func requireDropped(t *testing.T, msg protoreflect.Message) {
+	t.Helper()
+	unknownconnect.DropUnknownFields(msg)
+	require.False(t, unknownconnect.MessageHasUnknownFields(msg))
+	b, err := proto.Marshal(msg.Interface())
+	require.NoError(t, err)
+	roundTrip := msg.New().Interface()
+	require.NoError(t, proto.Unmarshal(b, roundTrip))
+	require.False(t, unknownconnect.MessageHasUnknownFields(roundTrip.ProtoReflect()))
+}
+
+func TestProto2Groups(t *testing.T) {
+	msg := &legacy.Order{}
+	require.NoError(t, proto.Unmarshal(protopack.Message{
+		protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("o-1"),
+		protopack.Tag{Number: 2, Type: protopack.StartGroupType},
+		protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("pen"),
+		protopack.Tag{Number: 9, Type: protopack.VarintType}, protopack.Varint(1),
+		protopack.Tag{Number: 2, Type: protopack.EndGroupType},
+		protopack.Tag{Number: 3, Type: protopack.StartGroupType},
+		protopack.Tag{Number: 1, Type: protopack.VarintType}, protopack.Varint(2),
+		protopack.Tag{Number: 3, Type: protopack.EndGroupType},
+		protopack.Tag{Number: 3, Type: protopack.StartGroupType},
+		protopack.Tag{Number: 9, Type: protopack.Fixed32Type}, protopack.Uint32(3),
+		protopack.Tag{Number: 3, Type: protopack.EndGroupType},
+		// an unknown group
+		protopack.Tag{Number: 20, Type: protopack.StartGroupType},
+		protopack.Tag{Number: 1, Type: protopack.VarintType}, protopack.Varint(4),
+		protopack.Tag{Number: 20, Type: protopack.EndGroupType},
+	}.Marshal(), msg))
+	drift := protopack.Message{
+		protopack.Tag{Number: 4, Type: protopack.VarintType}, protopack.Varint(7),
+		protopack.Tag{Number: 5, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{protopack.Varint(1), protopack.Varint(7)}},
+		protopack.Tag{Number: 6, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+			protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("x"),
+			protopack.Tag{Number: 2, Type: protopack.VarintType}, protopack.Varint(7),
+		}},
+	}
+	addUnknown(msg.ProtoReflect(), drift)
+
+	report := unknownconnect.NewReport(msg.ProtoReflect())
+	assert.Equal(t, []string{
+		"#20: new",
+		"#4: enum_drift",
+		"#5: enum_drift",
+		"#6: enum_drift",
+		"item#9: new",
+		"entry[1]#9: new",
+	}, reportKinds(report))
+	assert.Equal(t, protowire.StartGroupType, report.Fields[0].Type)
+	assert.Equal(t, ".: 20 { 1: 4 }", report.Fields[0].String())
+	requireDropped(t, msg.ProtoReflect())
+
+	t.Run("unmarshalled", func(t *testing.T) {
+		// the Go runtime keeps values closed enums don't define as set, like those of open enums
+		msg := &legacy.Order{}
+		require.NoError(t, proto.Unmarshal(drift.Marshal(), msg))
+		assert.Equal(t, legacy.Color(7), msg.GetColor())
+		assert.Equal(t, []legacy.Color{legacy.Color_GREEN, 7}, msg.GetColors())
+		assert.Equal(t, map[string]legacy.Color{"x": 7}, msg.GetByName())
+		assert.False(t, unknownconnect.MessageHasUnknownFields(msg.ProtoReflect()))
+
+		// reports list them all the same, encoded on their own
+		report := unknownconnect.NewReport(msg.ProtoReflect())
+		assert.Equal(t, []string{
+			"#4: enum_drift",
+			"#5: enum_drift",
+			"#6: enum_drift",
+		}, reportKinds(report))
+		assert.Equal(t, protopack.Message{protopack.Tag{Number: 5, Type: protopack.VarintType}, protopack.Varint(7)}.Marshal(), report.Fields[1].Raw)
+		assert.Equal(t, drift[4:].Marshal(), report.Fields[2].Raw)
+
+		// and so do interceptors, which call their callbacks for the message
+		var called bool
+		unary := unknownconnect.NewInterceptor(unknownconnect.WithCallback(func(context.Context, connect.Spec, proto.Message) error {
+			called = true
+			return nil
+		})).WrapUnary(func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		})
+		_, err := unary(context.Background(), connect.NewRequest(msg))
+		require.NoError(t, err)
+		assert.True(t, called)
+
+		msg.Colors[1] = legacy.Color_RED
+		msg.Color = legacy.Color_GREEN.Enum()
+		msg.ByName["x"] = legacy.Color_RED
+		assert.True(t, unknownconnect.NewReport(msg.ProtoReflect()).Empty())
+	})
+}
+
+func TestEditions(t *testing.T) {
+	md := editionsDescriptor(t)
+	require.Equal(t, protoreflect.GroupKind, md.Fields().ByName("detail").Kind(), "delimited messages are encoded like groups")
+
+	msg := dynamicpb.NewMessage(md)
+	require.NoError(t, proto.Unmarshal(protopack.Message{
+		protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("square"),
+		protopack.Tag{Number: 2, Type: protopack.StartGroupType},
+		protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("big"),
+		protopack.Tag{Number: 9, Type: protopack.VarintType}, protopack.Varint(1),
+		protopack.Tag{Number: 2, Type: protopack.EndGroupType},
+		protopack.Tag{Number: 5, Type: protopack.StartGroupType},
+		protopack.Tag{Number: 8, Type: protopack.VarintType}, protopack.Varint(1),
+		protopack.Tag{Number: 5, Type: protopack.EndGroupType},
+	}.Marshal(), msg))
+	addUnknown(msg, protopack.Message{
+		protopack.Tag{Number: 3, Type: protopack.VarintType}, protopack.Varint(5),
+		protopack.Tag{Number: 4, Type: protopack.VarintType}, protopack.Varint(5),
+	})
+
+	// Color is closed by the file's features, Shade is open
+	assert.Equal(t, []string{
+		"#3: enum_drift",
+		"#4: type_mismatch",
+		"detail#9: new",
+		"part[0]#8: new",
+	}, reportKinds(unknownconnect.NewReport(msg)))
+	requireDropped(t, msg)
+}
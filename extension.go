@@ -0,0 +1,81 @@
+package unknownconnect
+
+import (
+	"fmt"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ResolveExtensions parses the unknown fields of msg and its nested messages that are extensions
+// known to resolver, as if msg had been unmarshalled with it. Messages held by those extensions are
+// then scanned like any other nested message. Unknown fields in extension ranges that resolver
+// doesn't know are left alone, and reported with KindUnregisteredExtension.
+func ResolveExtensions(msg protoreflect.Message, resolver protoregistry.ExtensionTypeResolver) error {
+	var err error
+	ForEachUnknownField(msg, func(msg protoreflect.Message) bool {
+		err = resolveExtensions(msg, resolver)
+		return err == nil
+	})
+	return err
+}
+
+// resolveExtensions resolves the extensions of a received message with the resolver of the options,
+// if any. Like scanning, it stops at the scan limits, and the message is rejected if they are
+// exceeded with LimitReject.
+func (opts *interceptorOpts) resolveExtensions(msg proto.Message) error {
+	if opts.extensionResolver == nil {
+		return nil
+	}
+	var err error
+	exceeded := walkUnknownFields(msg.ProtoReflect(), opts.limits, func(_ path, msg protoreflect.Message) bool {
+		err = resolveExtensions(msg, opts.extensionResolver)
+		return err == nil
+	})
+	if err != nil {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if exceeded && opts.limits.OnLimit == LimitReject {
+		return connect.NewError(connect.CodeResourceExhausted, ErrScanLimitExceeded)
+	}
+	return nil
+}
+
+// resolveExtensions resolves the extensions in the unknown fields of msg itself. The walker looks
+// at the fields of a message after passing it to its callback, so it goes on into the messages of
+// the extensions resolved here.
+func resolveExtensions(msg protoreflect.Message, resolver protoregistry.ExtensionTypeResolver) error {
+	md := msg.Descriptor()
+	if md.ExtensionRanges().Len() == 0 {
+		return nil
+	}
+	var unknown, resolved []byte
+	for b := msg.GetUnknown(); len(b) > 0; {
+		num, _, n := protowire.ConsumeField(b)
+		if n < 0 {
+			unknown = append(unknown, b...)
+			break
+		}
+		if md.ExtensionRanges().Has(num) {
+			if _, err := resolver.FindExtensionByNumber(md.FullName(), num); err == nil {
+				resolved = append(resolved, b[:n]...)
+				b = b[n:]
+				continue
+			}
+		}
+		unknown = append(unknown, b[:n]...)
+		b = b[n:]
+	}
+	if len(resolved) == 0 {
+		return nil
+	}
+	msg.SetUnknown(unknown)
+	err := proto.UnmarshalOptions{Merge: true, AllowPartial: true, Resolver: resolver}.Unmarshal(resolved, msg.Interface())
+	if err != nil {
+		return fmt.Errorf("resolving extensions of %s: %w", md.FullName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package unknownconnect_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/protobuild"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/testing/protopack"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// extensionFile describes a proto2 message that can be extended:
+//
+//	message Host {
+//	  optional string name = 1;
+//	  extensions 100 to 199;
+//	}
+//	message Detail {
+//	  optional string note = 1;
+//	}
+//	extend Host {
+//	  optional Detail detail = 100;
+//	  optional int32 score = 101;
+//	}
+//
+// It isn't generated, since generated extensions are registered globally and these have to stay
+// unregistered unless a test registers them.
+func extensionFile(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+	host := protobuild.Message("Host", protobuild.Field("name", 1, protobuild.TypeString, ""))
+	host.ExtensionRange = []*descriptorpb.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("host.proto"),
+		Package:     proto.String("hosts"),
+		Syntax:      proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{host, protobuild.Message("Detail", protobuild.Field("note", 1, protobuild.TypeString, ""))},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			protobuild.Field("detail", 100, protobuild.TypeMessage, ".hosts.Detail").Extends(".hosts.Host").Proto(),
+			protobuild.Field("score", 101, protobuild.TypeInt32, "").Extends(".hosts.Host").Proto(),
+		},
+	}, nil)
+	require.NoError(t, err)
+	return fd
+}
+
+// newHost returns a Host with the detail extension, holding an unknown field, the score extension
+// and an extension that isn't declared at all, none of which are registered.
+func newHost(t *testing.T, fd protoreflect.FileDescriptor) *dynamicpb.Message {
+	t.Helper()
+	host := dynamicpb.NewMessage(fd.Messages().ByName("Host"))
+	require.NoError(t, proto.Unmarshal(protopack.Message{
+		protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("example.com"),
+		protopack.Tag{Number: 100, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+			protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("note"),
+			protopack.Tag{Number: 5, Type: protopack.VarintType}, protopack.Varint(1),
+		}},
+		protopack.Tag{Number: 101, Type: protopack.VarintType}, protopack.Varint(7),
+		protopack.Tag{Number: 150, Type: protopack.VarintType}, protopack.Varint(1),
+		protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1),
+	}.Marshal(), host))
+	return host
+}
+
+// reportKinds lists the fields of a report as "path#number: kind".
+func reportKinds(report *unknownconnect.Report) []string {
+	var kinds []string
+	for _, f := range report.Fields {
+		kinds = append(kinds, fmt.Sprintf("%s#%d: %s", f.Path, f.Number, f.Kind))
+	}
+	return kinds
+}
+
+func TestResolveExtensions(t *testing.T) {
+	fd := extensionFile(t)
+
+	t.Run("unregistered", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"#100: unregistered_extension",
+			"#101: unregistered_extension",
+			"#150: unregistered_extension",
+			"#300: new",
+		}, reportKinds(unknownconnect.NewReport(newHost(t, fd))))
+	})
+	t.Run("resolved", func(t *testing.T) {
+		var types protoregistry.Types
+		require.NoError(t, types.RegisterExtension(dynamicpb.NewExtensionType(fd.Extensions().ByName("detail"))))
+		host := newHost(t, fd)
+		require.NoError(t, unknownconnect.ResolveExtensions(host, &types))
+		assert.Equal(t, []string{
+			"#101: unregistered_extension",
+			"#150: unregistered_extension",
+			"#300: new",
+			"[hosts.detail]#5: new",
+		}, reportKinds(unknownconnect.NewReport(host)))
+	})
+	t.Run("interceptor", func(t *testing.T) {
+		var types protoregistry.Types
+		for _, name := range []protoreflect.Name{"detail", "score"} {
+			require.NoError(t, types.RegisterExtension(dynamicpb.NewExtensionType(fd.Extensions().ByName(name))))
+		}
+		var report *unknownconnect.Report
+		interceptor := unknownconnect.NewInterceptor(
+			unknownconnect.WithExtensionResolver(&types),
+			unknownconnect.WithReportCallback(func(ctx context.Context, s connect.Spec, r *unknownconnect.Report) error {
+				report = r
+				return nil
+			}),
+		)
+		unary := interceptor.WrapUnary(connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		}))
+		_, err := unary(context.Background(), connect.NewRequest(newHost(t, fd)))
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"#150: unregistered_extension",
+			"#300: new",
+			"[hosts.detail]#5: new",
+		}, reportKinds(report))
+	})
+	t.Run("delimited reader", func(t *testing.T) {
+		var types protoregistry.Types
+		for _, name := range []protoreflect.Name{"detail", "score"} {
+			require.NoError(t, types.RegisterExtension(dynamicpb.NewExtensionType(fd.Extensions().ByName(name))))
+		}
+		var buf bytes.Buffer
+		_, err := protodelim.MarshalTo(&buf, newHost(t, fd))
+		require.NoError(t, err)
+
+		var callbackReport *unknownconnect.Report
+		reader := unknownconnect.NewDelimitedReader(&buf,
+			unknownconnect.WithExtensionResolver(&types),
+			unknownconnect.WithReportCallback(func(ctx context.Context, s connect.Spec, r *unknownconnect.Report) error {
+				callbackReport = r
+				return nil
+			}),
+		)
+		report, err := reader.Read(dynamicpb.NewMessage(fd.Messages().ByName("Host")))
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		// the resolved extensions aren't reported as unregistered
+		assert.Equal(t, []string{
+			"#150: unregistered_extension",
+			"#300: new",
+			"[hosts.detail]#5: new",
+		}, reportKinds(&report.Report))
+		assert.Equal(t, reportKinds(callbackReport), reportKinds(&report.Report))
+	})
+	t.Run("interceptor bounds", func(t *testing.T) {
+		var types protoregistry.Types
+		require.NoError(t, types.RegisterExtension(dynamicpb.NewExtensionType(fd.Extensions().ByName("detail"))))
+		call := func(interceptor connect.Interceptor, host *dynamicpb.Message) error {
+			unary := interceptor.WrapUnary(connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+				return nil, nil
+			}))
+			_, err := unary(context.Background(), connect.NewRequest(host))
+			return err
+		}
+
+		// extensions are resolved within the scan limits
+		host := newHost(t, fd)
+		err := call(unknownconnect.NewInterceptor(
+			unknownconnect.WithExtensionResolver(&types),
+			unknownconnect.WithScanLimits(unknownconnect.ScanLimits{MaxUnknownBytes: 8, OnLimit: unknownconnect.LimitReject}),
+		), host)
+		assert.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+		assert.Contains(t, reportKinds(unknownconnect.NewReport(host)), "#100: unregistered_extension")
+
+		// and only in sampled messages
+		host = newHost(t, fd)
+		require.NoError(t, call(unknownconnect.NewInterceptor(
+			unknownconnect.WithExtensionResolver(&types),
+			unknownconnect.WithSampling(unknownconnect.Sampling{}),
+		), host))
+		assert.Contains(t, reportKinds(unknownconnect.NewReport(host)), "#100: unregistered_extension")
+
+		// unless unknown fields are dropped, which would lose them
+		host = newHost(t, fd)
+		require.NoError(t, call(unknownconnect.NewInterceptor(
+			unknownconnect.WithExtensionResolver(&types),
+			unknownconnect.WithSampling(unknownconnect.Sampling{}),
+			unknownconnect.WithDrop(),
+		), host))
+		assert.Empty(t, unknownconnect.NewReport(host).Fields)
+		detail, err := types.FindExtensionByName("hosts.detail")
+		require.NoError(t, err)
+		assert.True(t, host.Has(detail.TypeDescriptor()))
+	})
+}
@@ -0,0 +1,235 @@
+package unknownconnect
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxRawDepth is the deepest level of nested messages that FormatRaw renders. Deeper values are
+// printed as quoted bytes, so that a small, deeply nested payload can't blow up the output.
+const maxRawDepth = 32
+
+// FormatRaw renders wire-format bytes the way `protoc --decode_raw` does, one field per line,
+// guessing whether length-delimited values are strings or nested messages. It is meant for the Raw
+// bytes of an UnknownField, which can't be decoded with a schema. Messages nested more than 32
+// levels deep are rendered as quoted bytes.
+func FormatRaw(b []byte) string {
+	f := rawFormatter{newline: "\n", indent: "  "}
+	f.write(b, 0)
+	return strings.TrimSuffix(f.sb.String(), "\n")
+}
+
+// formatRawCompact renders wire-format bytes like FormatRaw, but on a single line.
+func formatRawCompact(b []byte) string {
+	f := rawFormatter{newline: " "}
+	f.write(b, 0)
+	return strings.TrimSuffix(f.sb.String(), " ")
+}
+
+// WireTypeName returns the name of a wire type, like "varint" or "bytes".
+func WireTypeName(typ protowire.Type) string {
+	switch typ {
+	case protowire.VarintType:
+		return "varint"
+	case protowire.Fixed32Type:
+		return "fixed32"
+	case protowire.Fixed64Type:
+		return "fixed64"
+	case protowire.BytesType:
+		return "bytes"
+	case protowire.StartGroupType:
+		return "group"
+	case protowire.EndGroupType:
+		return "end_group"
+	default:
+		return fmt.Sprintf("wire type %d", typ)
+	}
+}
+
+// String renders the field on a single line, like `user: 300: "hi"`.
+func (f UnknownField) String() string {
+	p := f.Path
+	if p == "" {
+		p = "."
+	}
+	switch {
+	case f.Name != "" && f.HMAC != nil:
+		return fmt.Sprintf("%s: %q: %s", p, f.Name, f.redactedString())
+	case f.Name != "":
+		return fmt.Sprintf("%s: %q: %s", p, f.Name, f.Raw)
+	case f.HMAC != nil:
+		return fmt.Sprintf("%s: %d: %s", p, f.Number, f.redactedString())
+	case f.Number == 0:
+		return fmt.Sprintf("%s: <malformed: %q>", p, f.Raw)
+	default:
+		return fmt.Sprintf("%s: %s", p, formatRawCompact(f.Raw))
+	}
+}
+
+// LogValue implements slog.LogValuer.
+func (f UnknownField) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("path", f.Path)}
+	switch {
+	case f.Name != "":
+		attrs = append(attrs, slog.String("key", f.Name))
+	case f.Number == 0:
+		if f.HMAC == nil {
+			return slog.GroupValue(append(attrs, slog.String("malformed", fmt.Sprintf("%q", f.Raw)))...)
+		}
+	default:
+		attrs = append(attrs, slog.Int("number", int(f.Number)), slog.String("wire_type", WireTypeName(f.Type)))
+	}
+	if f.Kind != KindNew {
+		attrs = append(attrs, slog.String("kind", f.Kind.String()))
+	}
+	switch {
+	case f.HMAC != nil:
+		attrs = append(attrs, slog.String("value", f.redactedString()))
+	case f.Name != "":
+		attrs = append(attrs, slog.String("value", string(f.Raw)))
+	default:
+		attrs = append(attrs, slog.String("value", formatRawCompact(f.Raw)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue implements slog.LogValuer, so a report passed to a logger shows what the unknown fields
+// contain instead of their raw bytes. Fields are grouped under their index in the report.
+func (r *Report) LogValue() slog.Value {
+	fields := make([]slog.Attr, len(r.Fields))
+	for i, f := range r.Fields {
+		fields[i] = slog.Any(strconv.Itoa(i), f)
+	}
+	attrs := []slog.Attr{slog.Int("count", len(r.Fields)), slog.Int("size", r.Size())}
+	if r.Truncated {
+		attrs = append(attrs, slog.Bool("truncated", true))
+	}
+	attrs = append(attrs, slog.Attr{Key: "fields", Value: slog.GroupValue(fields...)})
+	if len(r.Deprecated) > 0 {
+		deprecated := make([]string, len(r.Deprecated))
+		for i, u := range r.Deprecated {
+			deprecated[i] = u.String()
+		}
+		attrs = append(attrs, slog.Any("deprecated", deprecated))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// rawFormatter writes fields separated by newline, with nested messages indented by indent.
+type rawFormatter struct {
+	sb      strings.Builder
+	newline string
+	indent  string
+}
+
+func (f *rawFormatter) line(depth int, format string, args ...any) {
+	for i := 0; i < depth; i++ {
+		f.sb.WriteString(f.indent)
+	}
+	fmt.Fprintf(&f.sb, format, args...)
+	f.sb.WriteString(f.newline)
+}
+
+func (f *rawFormatter) write(b []byte, depth int) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			f.line(depth, "<invalid: %q>", b)
+			return
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				f.line(depth, "%d: <invalid varint>", num)
+				return
+			}
+			f.line(depth, "%d: %d", num, v)
+			b = b[n:]
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(b)
+			if n < 0 {
+				f.line(depth, "%d: <invalid fixed32>", num)
+				return
+			}
+			f.line(depth, "%d: 0x%08x", num, v)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				f.line(depth, "%d: <invalid fixed64>", num)
+				return
+			}
+			f.line(depth, "%d: 0x%016x", num, v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				f.line(depth, "%d: <invalid length-delimited value>", num)
+				return
+			}
+			switch {
+			case isPrintable(v):
+				f.line(depth, "%d: %s", num, strconv.Quote(string(v)))
+			case depth < maxRawDepth && isMessage(v):
+				f.line(depth, "%d {", num)
+				f.write(v, depth+1)
+				f.line(depth, "}")
+			default:
+				f.line(depth, "%d: %q", num, v)
+			}
+			b = b[n:]
+		case protowire.StartGroupType:
+			v, n := protowire.ConsumeGroup(num, b)
+			if n < 0 {
+				f.line(depth, "%d: <invalid group>", num)
+				return
+			}
+			if depth < maxRawDepth {
+				f.line(depth, "%d {", num)
+				f.write(v, depth+1)
+				f.line(depth, "}")
+			} else {
+				f.line(depth, "%d: %q", num, v)
+			}
+			b = b[n:]
+		default:
+			f.line(depth, "%d: <invalid wire type %d>", num, typ)
+			return
+		}
+	}
+}
+
+// isMessage returns true if b parses as a non-empty sequence of valid fields.
+func isMessage(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	for len(b) > 0 {
+		_, _, n := protowire.ConsumeField(b)
+		if n < 0 {
+			return false
+		}
+		b = b[n:]
+	}
+	return true
+}
+
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
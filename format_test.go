@@ -0,0 +1,72 @@
+package unknownconnect_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+func TestFormatRaw(t *testing.T) {
+	raw := protopack.Message{
+		protopack.Tag{Number: 1, Type: protopack.VarintType}, protopack.Varint(150),
+		protopack.Tag{Number: 2, Type: protopack.BytesType}, protopack.String("hi"),
+		protopack.Tag{Number: 3, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+			protopack.Tag{Number: 1, Type: protopack.Fixed32Type}, protopack.Uint32(7),
+		}},
+		protopack.Tag{Number: 4, Type: protopack.BytesType}, protopack.Bytes{0xff},
+	}.Marshal()
+
+	assert.Equal(t, `1: 150
+2: "hi"
+3 {
+  1: 0x00000007
+}
+4: "\xff"`, unknownconnect.FormatRaw(raw))
+
+	t.Run("malformed", func(t *testing.T) {
+		assert.Equal(t, "1: <invalid varint>", unknownconnect.FormatRaw([]byte{0x08, 0xff}))
+	})
+	t.Run("deeply nested", func(t *testing.T) {
+		raw := protowire.AppendTag(nil, 1, protowire.VarintType)
+		raw = protowire.AppendVarint(raw, 1)
+		for i := 0; i < 2000; i++ {
+			raw = protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), raw)
+		}
+		formatted := unknownconnect.FormatRaw(raw)
+		lines := strings.Split(formatted, "\n")
+		assert.Len(t, lines, 65)
+		assert.True(t, strings.HasPrefix(lines[32], strings.Repeat("  ", 32)+`1: "\n`), lines[32])
+		assert.Less(t, len(formatted), 5*len(raw))
+	})
+}
+
+func TestReportLogValue(t *testing.T) {
+	user := &new.User{Name: "bob"}
+	user.ProtoReflect().SetUnknown(protopack.Message{
+		protopack.Tag{Number: 300, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+			protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("hi"),
+		}},
+	}.Marshal())
+	report := unknownconnect.NewReport((&new.NewUserRequest{User: user}).ProtoReflect())
+
+	assert.Equal(t, `user: 300 { 1: "hi" }`, report.Fields[0].String())
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Warn("unknown fields", slog.Any("report", report))
+	assert.Equal(t, `level=WARN msg="unknown fields" report.count=1 report.size=7 report.fields.0.path=user report.fields.0.number=300 report.fields.0.wire_type=bytes report.fields.0.value="300 { 1: \"hi\" }"`+"\n", buf.String())
+}
@@ -0,0 +1,174 @@
+package unknownconnect_test
+
+import (
+	"testing"
+
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+// fuzzMessages are the types every fuzz input is unmarshalled into.
+var fuzzMessages = []func() proto.Message{
+	func() proto.Message { return &new.NewUserRequest{} },
+	func() proto.Message { return &old.NewUserRequest{} },
+	func() proto.Message { return &new.NewUserResponse{} },
+	func() proto.Message { return &old.NewUserResponse{} },
+}
+
+func addFuzzSeeds(f *testing.F) {
+	unknown := protopack.Message{protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1)}
+	user := protopack.Message{
+		protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("bob"),
+		protopack.Tag{Number: 2, Type: protopack.BytesType}, protopack.String("bob@example.com"),
+	}
+	seeds := []protopack.Message{
+		{},
+		unknown,
+		{protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.LengthPrefix{append(user, unknown...)}},
+		{
+			protopack.Tag{Number: 2, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+				protopack.Tag{Number: 1, Type: protopack.VarintType}, protopack.Varint(1),
+				protopack.Tag{Number: 2, Type: protopack.VarintType}, protopack.Varint(2),
+			}},
+			protopack.Tag{Number: 4, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{protopack.Varint(1), protopack.Varint(2)}},
+		},
+		{
+			protopack.Tag{Number: 3, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+				protopack.Tag{Number: 1, Type: protopack.VarintType}, protopack.Varint(7),
+				protopack.Tag{Number: 2, Type: protopack.BytesType}, protopack.LengthPrefix{unknown},
+			}},
+			protopack.Tag{Number: 3, Type: protopack.BytesType}, protopack.LengthPrefix{protopack.Message{
+				protopack.Tag{Number: 1, Type: protopack.VarintType}, protopack.Varint(8),
+				protopack.Tag{Number: 2, Type: protopack.BytesType}, protopack.LengthPrefix{user},
+			}},
+		},
+		{
+			protopack.Tag{Number: 5, Type: protopack.BytesType}, protopack.LengthPrefix{user},
+			protopack.Tag{Number: 5, Type: protopack.BytesType}, protopack.LengthPrefix{unknown},
+		},
+		// a known field with the wrong wire type
+		{protopack.Tag{Number: 1, Type: protopack.Fixed64Type}, protopack.Uint64(1)},
+		{protopack.Tag{Number: 1, Type: protopack.StartGroupType}, unknown, protopack.Tag{Number: 1, Type: protopack.EndGroupType}},
+	}
+	for _, seed := range seeds {
+		f.Add(seed.Marshal())
+	}
+	f.Add([]byte{0xff, 0xff})
+}
+
+// fuzzUnmarshal calls fn with b unmarshalled into each of the fuzzMessages that accept it.
+func fuzzUnmarshal(b []byte, fn func(msg protoreflect.Message)) {
+	for _, newMsg := range fuzzMessages {
+		msg := newMsg()
+		if proto.Unmarshal(b, msg) == nil {
+			fn(msg.ProtoReflect())
+		}
+	}
+}
+
+func FuzzForEachUnknownField(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		fuzzUnmarshal(b, func(msg protoreflect.Message) {
+			unknownconnect.ForEachUnknownField(msg, func(m protoreflect.Message) bool {
+				if len(m.GetUnknown()) == 0 {
+					t.Errorf("callback called for %s without unknown fields", m.Descriptor().FullName())
+				}
+				return true
+			})
+			unknownconnect.ForEachUnknownField(msg, func(protoreflect.Message) bool {
+				return false
+			})
+		})
+	})
+}
+
+func FuzzMessageHasUnknownFields(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		fuzzUnmarshal(b, func(msg protoreflect.Message) {
+			has := unknownconnect.MessageHasUnknownFields(msg)
+			if want := wireHasUnknown(t, msg); has != want {
+				t.Errorf("MessageHasUnknownFields(%s) returned %t, want %t", msg.Descriptor().FullName(), has, want)
+			}
+		})
+	})
+}
+
+func FuzzDropUnknownFields(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		fuzzUnmarshal(b, func(msg protoreflect.Message) {
+			unknownconnect.DropUnknownFields(msg)
+			roundTrip := msg.New().Interface()
+			if err := proto.Unmarshal(marshal(t, msg), roundTrip); err != nil {
+				t.Fatal(err)
+			}
+			if unknownconnect.MessageHasUnknownFields(roundTrip.ProtoReflect()) || wireHasUnknown(t, roundTrip.ProtoReflect()) {
+				t.Errorf("%s still has unknown fields after dropping them", msg.Descriptor().FullName())
+			}
+		})
+	})
+}
+
+func marshal(t *testing.T, msg protoreflect.Message) []byte {
+	t.Helper()
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg.Interface())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// wireHasUnknown is the brute-force version of MessageHasUnknownFields: it marshals msg and looks
+// for fields its type doesn't have, or has with another wire type, in the wire format.
+func wireHasUnknown(t *testing.T, msg protoreflect.Message) bool {
+	t.Helper()
+	unknown, err := wireFieldsUnknown(marshal(t, msg), msg.Descriptor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unknown
+}
+
+func wireFieldsUnknown(b []byte, md protoreflect.MessageDescriptor) (bool, error) {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return false, protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return false, protowire.ParseError(n)
+		}
+		value := b[:n]
+		b = b[n:]
+
+		fd := md.Fields().ByNumber(num)
+		if fd == nil {
+			return true, nil
+		}
+		switch {
+		case typ == protowire.BytesType && fd.Message() != nil:
+			inner, _ := protowire.ConsumeBytes(value)
+			if unknown, err := wireFieldsUnknown(inner, fd.Message()); unknown || err != nil {
+				return unknown, err
+			}
+		case typ == protowire.StartGroupType && fd.Kind() == protoreflect.GroupKind:
+			inner, _ := protowire.ConsumeGroup(num, value)
+			if unknown, err := wireFieldsUnknown(inner, fd.Message()); unknown || err != nil {
+				return unknown, err
+			}
+		case !wire.Accepts(fd, typ):
+			return true, nil
+		}
+	}
+	return false, nil
+}
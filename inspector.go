@@ -0,0 +1,36 @@
+package unknownconnect
+
+import (
+	"context"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+)
+
+// Inspector inspects messages outside of an RPC, like messages read from files or captured by a
+// Capturer, the same way an interceptor created with the same options would.
+type Inspector struct {
+	opts *interceptorOpts
+}
+
+// NewInspector creates a new Inspector.
+func NewInspector(opts ...option) *Inspector {
+	return &Inspector{opts: newInterceptorOpts(opts)}
+}
+
+// Inspect inspects a message received for the RPC described by spec, calling callbacks and applying
+// limits and WithDrop as an interceptor would. Per-stream limits apply to the single message.
+func (i *Inspector) Inspect(ctx context.Context, spec connect.Spec, header http.Header, msg proto.Message) error {
+	return handleMessage(ctx, msg, spec, func() http.Header { return header }, i.opts, &streamUsage{})
+}
+
+// Replay unmarshals a captured message into msg and inspects it with the procedure, direction and
+// headers it was captured with. msg doesn't have to be of the captured type, which allows replaying
+// captures against other versions of the schema.
+func (i *Inspector) Replay(ctx context.Context, captured *CapturedMessage, msg proto.Message) error {
+	if err := proto.Unmarshal(captured.Payload, msg); err != nil {
+		return err
+	}
+	return i.Inspect(ctx, captured.Spec(), captured.Header, msg)
+}
@@ -6,6 +6,7 @@ import (
 
 	"connectrpc.com/connect"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 var _ connect.Interceptor = (*interceptor)(nil) // we make sure it implements the interface
@@ -15,9 +16,27 @@ var _ connect.Interceptor = (*interceptor)(nil) // we make sure it implements th
 // be nested deeper into this given message.
 type UnknownCallback func(context.Context, connect.Spec, proto.Message) error
 
+// ReportCallback is called with a report of every unknown field whenever a message has any. Unlike
+// UnknownCallback, it also sees unknown JSON keys found by a JSONCodec.
+type ReportCallback func(context.Context, connect.Spec, *Report) error
+
 type interceptorOpts struct {
-	drop      bool
-	callbacks []UnknownCallback
+	drop              bool
+	dropRules         *DropRules
+	callbacks         []UnknownCallback
+	reportCallbacks   []ReportCallback
+	staleCallbacks    []ReportCallback
+	limits            ScanLimits
+	unknownLimits     UnknownLimits
+	metrics           Metrics
+	sampler           *sampler
+	redactor          *redactor
+	capturer          *Capturer
+	chaos             *Chaos
+	deprecated        bool
+	extensionResolver protoregistry.ExtensionTypeResolver
+	jsonCodec         *JSONCodec
+	protoCodec        *ProtoCodec
 }
 
 type interceptor struct {
@@ -29,21 +48,28 @@ type interceptor struct {
 // a field is being given to this client/server that does not. The callback can decide what to do.
 // Any error returned from the callback will be used as an error in the request or response.
 func NewInterceptor(opts ...option) *interceptor {
+	return &interceptor{opts: newInterceptorOpts(opts)}
+}
+
+func newInterceptorOpts(opts []option) *interceptorOpts {
 	o := &interceptorOpts{
 		callbacks: []UnknownCallback{},
 	}
 	for _, opt := range opts {
 		opt(o)
 	}
-	return &interceptor{opts: o}
+	return o
 }
 
 func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
 		spec := req.Spec()
 		isClient := spec.IsClient
+		if isClient && i.opts.chaos != nil {
+			i.opts.chaos.inject(spec, req.Any())
+		}
 		if !isClient {
-			if err := handleMessage(ctx, req.Any(), spec, i.opts); err != nil {
+			if err := handleMessage(ctx, req.Any(), spec, req.Header, i.opts, &streamUsage{}); err != nil {
 				return nil, err
 			}
 		}
@@ -51,8 +77,11 @@ func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 		if err != nil {
 			return resp, err
 		}
+		if !isClient && i.opts.chaos != nil && resp != nil {
+			i.opts.chaos.inject(spec, resp.Any())
+		}
 		if isClient {
-			if err := handleMessage(ctx, resp.Any(), spec, i.opts); err != nil {
+			if err := handleMessage(ctx, resp.Any(), spec, resp.Header, i.opts, &streamUsage{}); err != nil {
 				return resp, err
 			}
 		}
@@ -85,16 +114,24 @@ func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) co
 
 type wrappedHandlerConn struct {
 	connect.StreamingHandlerConn
-	ctx  context.Context
-	spec connect.Spec
-	opts *interceptorOpts
+	ctx   context.Context
+	spec  connect.Spec
+	opts  *interceptorOpts
+	usage streamUsage
 }
 
 func (w *wrappedHandlerConn) Receive(msg any) error {
-	if err := handleMessage(w.ctx, msg, w.spec, w.opts); err != nil {
+	if err := w.StreamingHandlerConn.Receive(msg); err != nil {
 		return err
 	}
-	return w.StreamingHandlerConn.Receive(msg)
+	return handleMessage(w.ctx, msg, w.spec, w.RequestHeader, w.opts, &w.usage)
+}
+
+func (w *wrappedHandlerConn) Send(msg any) error {
+	if w.opts.chaos != nil {
+		w.opts.chaos.inject(w.spec, msg)
+	}
+	return w.StreamingHandlerConn.Send(msg)
 }
 
 func (w *wrappedHandlerConn) RequestHeader() http.Header {
@@ -103,32 +140,165 @@ func (w *wrappedHandlerConn) RequestHeader() http.Header {
 
 type wrappedClientConn struct {
 	connect.StreamingClientConn
-	ctx  context.Context
-	spec connect.Spec
-	opts *interceptorOpts
+	ctx   context.Context
+	spec  connect.Spec
+	opts  *interceptorOpts
+	usage streamUsage
+}
+
+func (w *wrappedClientConn) Send(msg any) error {
+	if w.opts.chaos != nil {
+		w.opts.chaos.inject(w.spec, msg)
+	}
+	return w.StreamingClientConn.Send(msg)
 }
 
 func (w *wrappedClientConn) Receive(msg any) error {
-	if err := handleMessage(w.ctx, msg, w.spec, w.opts); err != nil {
+	if err := w.StreamingClientConn.Receive(msg); err != nil {
 		return err
 	}
-	return w.StreamingClientConn.Receive(msg)
+	return handleMessage(w.ctx, msg, w.spec, w.ResponseHeader, w.opts, &w.usage)
+}
+
+// handleMessage inspects a received message. header returns the headers the message came with, and
+// is only called when they are needed.
+func handleMessage(ctx context.Context, m any, spec connect.Spec, header func() http.Header, opts *interceptorOpts, usage *streamUsage) error {
+	_, err := inspectMessage(ctx, m, spec, header, opts, usage, false)
+	return err
 }
 
-func handleMessage(ctx context.Context, m any, spec connect.Spec, opts *interceptorOpts) error {
-	if msg, ok := (m).(proto.Message); ok {
-		if opts.drop {
-			defer func() {
-				DropUnknownFields(msg.ProtoReflect())
-			}()
+// inspectMessage is handleMessage, returning the redacted report the callbacks got. With wantReport,
+// the report is built even when no option needs it. The report is nil when there is nothing to
+// report, and for messages that weren't sampled.
+func inspectMessage(ctx context.Context, m any, spec connect.Spec, header func() http.Header, opts *interceptorOpts, usage *streamUsage, wantReport bool) (*Report, error) {
+	msg, ok := (m).(proto.Message)
+	if !ok {
+		return nil, nil
+	}
+	var jsonFields []UnknownField
+	if opts.jsonCodec != nil {
+		jsonFields = opts.jsonCodec.take(msg)
+	}
+	// the codec may already have checked the wire format, leaving nothing to walk or drop
+	clean := opts.protoCodec != nil && opts.protoCodec.isClean(msg)
+	if clean && !opts.deprecated {
+		return nil, nil
+	}
+	if opts.drop && !clean {
+		defer func() {
+			dropUnknownFields(msg.ProtoReflect(), opts.dropRules)
+		}()
+	}
+	if opts.sampler != nil {
+		ps := opts.sampler.procedure(spec.Procedure)
+		sampled, rate := opts.sampler.sample(ps)
+		if !sampled {
+			// dropping would lose the extensions the resolver knows
+			if opts.drop && !clean {
+				if err := opts.resolveExtensions(msg); err != nil {
+					return nil, err
+				}
+			}
+			return nil, enforceUnsampled(msg, jsonFields, clean, opts, usage)
 		}
-		if len(opts.callbacks) > 0 && MessageHasUnknownFields(msg.ProtoReflect()) {
+		opts.record(ctx, spec, MetricSamplingRate, "", rate)
+		start := opts.sampler.now()
+		defer func() {
+			opts.sampler.done(ps, spec.Procedure, opts.sampler.now().Sub(start))
+		}()
+	}
+	if !clean {
+		if err := opts.resolveExtensions(msg); err != nil {
+			return nil, err
+		}
+	}
+	needsReport := wantReport || len(opts.reportCallbacks) > 0 || len(opts.staleCallbacks) > 0 || opts.unknownLimits.enabled() || opts.metrics != nil
+	if !needsReport && len(opts.callbacks) == 0 && opts.capturer == nil && opts.limits.OnLimit != LimitReject {
+		return nil, nil
+	}
+
+	var report *Report
+	var hasUnknown bool
+	var err error
+	switch {
+	case clean:
+		report = &Report{}
+	case needsReport:
+		report, err = NewLimitedReport(msg.ProtoReflect(), opts.limits)
+		if err == nil {
+			report.Fields = append(jsonFields, report.Fields...)
+			hasUnknown = !report.Empty() || report.Truncated
+		}
+	default:
+		hasUnknown, err = hasUnknownFields(msg.ProtoReflect(), opts.limits)
+		hasUnknown = hasUnknown || len(jsonFields) > 0
+	}
+	if err != nil {
+		return nil, connect.NewError(connect.CodeResourceExhausted, err)
+	}
+	if opts.deprecated && needsReport {
+		var exceeded bool
+		report.Deprecated, exceeded = findDeprecated(msg.ProtoReflect(), opts.limits)
+		if exceeded {
+			switch opts.limits.OnLimit {
+			case LimitTruncate:
+				report.Truncated = true
+			case LimitReject:
+				return nil, connect.NewError(connect.CodeResourceExhausted, ErrScanLimitExceeded)
+			}
+		}
+		opts.recordDeprecated(ctx, spec, report.Deprecated)
+	}
+	if !hasUnknown && (report == nil || len(report.Deprecated) == 0) {
+		return nil, nil
+	}
+	if hasUnknown {
+		if report != nil {
+			if err := opts.checkUnknownLimits(ctx, spec, report, usage); err != nil {
+				return nil, err
+			}
+		}
+		// messages rejected for their unknown fields aren't captured, they could fill the files
+		if opts.capturer != nil && opts.redactor.allowsRaw(spec.Procedure) {
+			opts.capturer.capture(spec, header(), msg)
+		}
+		if len(opts.callbacks) > 0 {
+			redacted := opts.redactor.redactMessage(spec.Procedure, msg)
 			for _, cb := range opts.callbacks {
-				if err := cb(ctx, spec, msg); err != nil {
-					return err
+				if err := cb(ctx, spec, redacted); err != nil {
+					return nil, err
 				}
 			}
 		}
 	}
-	return nil
+	report = opts.redactor.redactReport(spec.Procedure, report)
+	for _, cb := range opts.reportCallbacks {
+		if err := cb(ctx, spec, report); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.staleCallbacks) > 0 {
+		if stale := report.OfKind(KindReserved); !stale.Empty() {
+			for _, cb := range opts.staleCallbacks {
+				if err := cb(ctx, spec, stale); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// enforceUnsampled holds a message that wasn't sampled to the unknown limits, which guard the service
+// rather than observe its peers, without reporting or recording anything about it.
+func enforceUnsampled(msg proto.Message, jsonFields []UnknownField, clean bool, opts *interceptorOpts, usage *streamUsage) error {
+	if clean || !opts.unknownLimits.enabled() {
+		return nil
+	}
+	report, err := NewLimitedReport(msg.ProtoReflect(), opts.limits)
+	if err != nil {
+		return connect.NewError(connect.CodeResourceExhausted, err)
+	}
+	report.Fields = append(jsonFields, report.Fields...)
+	return opts.enforceUnknownLimits(usage, len(report.Fields), report.Size(), nil)
 }
@@ -14,9 +14,11 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/sudorandom/unknownconnect-go"
 	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
 	"github.com/sudorandom/unknownconnect-go/internal/proto/old/oldconnect"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protopack"
 )
 
 func TestOutdatedClient(t *testing.T) {
@@ -187,3 +189,101 @@ func (c LocalClient) Do(req *http.Request) (*http.Response, error) {
 	c.handler.ServeHTTP(recorder, req)
 	return recorder.Result(), nil
 }
+
+type fakeHandlerConn struct {
+	connect.StreamingHandlerConn
+	body  []byte
+	codec connect.Codec
+}
+
+func (c *fakeHandlerConn) Spec() connect.Spec {
+	return connect.Spec{StreamType: connect.StreamTypeClient}
+}
+
+func (c *fakeHandlerConn) Receive(msg any) error {
+	if c.codec != nil {
+		return c.codec.Unmarshal(c.body, msg)
+	}
+	return proto.Unmarshal(c.body, msg.(proto.Message))
+}
+
+func TestInterceptorStreamingHandler(t *testing.T) {
+	body, err := proto.Marshal(&new.User{Name: "bob", Email: "bob@example.com"})
+	require.NoError(t, err)
+
+	var called bool
+	interceptor := unknownconnect.NewInterceptor(
+		unknownconnect.WithCallback(func(ctx context.Context, s connect.Spec, m proto.Message) error {
+			called = true
+			return nil
+		}),
+		unknownconnect.WithDrop(),
+	)
+	handler := interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		msg := &old.User{}
+		require.NoError(t, conn.Receive(msg))
+		assert.Equal(t, "bob", msg.Name)
+		assert.Empty(t, msg.ProtoReflect().GetUnknown())
+		return nil
+	})
+	require.NoError(t, handler(context.Background(), &fakeHandlerConn{body: body}))
+	assert.True(t, called)
+}
+
+func TestInterceptorUnknownLimits(t *testing.T) {
+	// 2 unknown fields, 20 bytes in total
+	user := &new.User{Name: "bob", Email: "bob@example.com"}
+	user.ProtoReflect().SetUnknown(protopack.Message{protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1)}.Marshal())
+	body, err := proto.Marshal(user)
+	require.NoError(t, err)
+
+	receive := func(limits unknownconnect.UnknownLimits, count int, metrics unknownconnect.Metrics) error {
+		interceptor := unknownconnect.NewInterceptor(unknownconnect.WithUnknownLimits(limits), unknownconnect.WithMetrics(metrics))
+		handler := interceptor.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			for i := 0; i < count; i++ {
+				if err := conn.Receive(&old.User{}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return handler(context.Background(), &fakeHandlerConn{body: body})
+	}
+
+	t.Run("within limits", func(t *testing.T) {
+		var measurements []unknownconnect.Measurement
+		metrics := unknownconnect.MetricsFunc(func(_ context.Context, m unknownconnect.Measurement) {
+			measurements = append(measurements, m)
+		})
+		err := receive(unknownconnect.UnknownLimits{MaxFieldsPerMessage: 2, MaxBytesPerStream: 40}, 1, metrics)
+		require.NoError(t, err)
+		type measurement struct {
+			name, label string
+			value       float64
+		}
+		var got []measurement
+		for _, m := range measurements {
+			got = append(got, measurement{m.Name, m.Label, m.Value})
+		}
+		assert.Equal(t, []measurement{
+			{unknownconnect.MetricUnknownFields, "", 2},
+			{unknownconnect.MetricUnknownBytes, "", 20},
+			{unknownconnect.MetricUnknownFieldsByKind, "new", 2},
+			{unknownconnect.MetricLimitUsage, "fields_per_message", 1},
+			{unknownconnect.MetricLimitUsage, "bytes_per_stream", 0.5},
+		}, got)
+	})
+	t.Run("per message", func(t *testing.T) {
+		err := receive(unknownconnect.UnknownLimits{MaxBytesPerMessage: 10}, 1, nil)
+		assert.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+		assert.ErrorIs(t, err, unknownconnect.ErrUnknownLimitExceeded)
+		assert.ErrorContains(t, err, "bytes_per_message: 20 > 10")
+	})
+	t.Run("per stream", func(t *testing.T) {
+		limits := unknownconnect.UnknownLimits{MaxFieldsPerStream: 5, Code: connect.CodeInvalidArgument}
+		require.NoError(t, receive(limits, 2, nil))
+		err := receive(limits, 3, nil)
+		assert.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+		assert.ErrorContains(t, err, "fields_per_stream: 6 > 5")
+	})
+}
@@ -0,0 +1,142 @@
+// Package protobuild builds descriptors for tests, for schemas that vary from test to test, that
+// protoc-gen-go can't generate, like editions files, or whose extensions mustn't be registered
+// globally like generated ones are. Fixed schemas belong in .proto files under internal/proto
+// instead.
+package protobuild
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	TypeString  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+	TypeInt32   = descriptorpb.FieldDescriptorProto_TYPE_INT32
+	TypeInt64   = descriptorpb.FieldDescriptorProto_TYPE_INT64
+	TypeSint64  = descriptorpb.FieldDescriptorProto_TYPE_SINT64
+	TypeFixed32 = descriptorpb.FieldDescriptorProto_TYPE_FIXED32
+	TypeDouble  = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+	TypeBytes   = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	TypeEnum    = descriptorpb.FieldDescriptorProto_TYPE_ENUM
+	TypeMessage = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+)
+
+// FieldSpec describes a field, singular unless List is called.
+type FieldSpec struct {
+	name     string
+	number   int32
+	typ      descriptorpb.FieldDescriptorProto_Type
+	typeName string
+	repeated bool
+	required bool
+	oneof    *int32
+	extendee string
+	options  *descriptorpb.FieldOptions
+}
+
+// Field describes a field of the given type. typeName is the name of the message or enum type,
+// either fully qualified with a leading dot or relative to the message holding the field.
+func Field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) FieldSpec {
+	return FieldSpec{name: name, number: number, typ: typ, typeName: typeName}
+}
+
+// List makes the field repeated.
+func (f FieldSpec) List() FieldSpec {
+	f.repeated = true
+	return f
+}
+
+// Required makes the field required, which only proto2 files allow.
+func (f FieldSpec) Required() FieldSpec {
+	f.required = true
+	return f
+}
+
+// InOneof puts the field in the oneof with the given index in its message.
+func (f FieldSpec) InOneof(index int32) FieldSpec {
+	f.oneof = &index
+	return f
+}
+
+// Extends makes the field an extension of the message with the given fully qualified name.
+func (f FieldSpec) Extends(extendee string) FieldSpec {
+	f.extendee = extendee
+	return f
+}
+
+// WithOptions sets the options of the field, like its features in an editions file.
+func (f FieldSpec) WithOptions(options *descriptorpb.FieldOptions) FieldSpec {
+	f.options = options
+	return f
+}
+
+// Proto returns the descriptor of the field.
+func (f FieldSpec) Proto() *descriptorpb.FieldDescriptorProto {
+	label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	switch {
+	case f.repeated:
+		label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	case f.required:
+		label = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED
+	}
+	fdp := &descriptorpb.FieldDescriptorProto{
+		Name:       proto.String(f.name),
+		Number:     proto.Int32(f.number),
+		Label:      label.Enum(),
+		Type:       f.typ.Enum(),
+		OneofIndex: f.oneof,
+		Options:    f.options,
+	}
+	if f.typeName != "" {
+		fdp.TypeName = proto.String(f.typeName)
+	}
+	if f.extendee != "" {
+		fdp.Extendee = proto.String(f.extendee)
+	}
+	return fdp
+}
+
+// Message describes a message with the given fields.
+func Message(name string, fields ...FieldSpec) *descriptorpb.DescriptorProto {
+	dp := &descriptorpb.DescriptorProto{Name: proto.String(name)}
+	for _, f := range fields {
+		dp.Field = append(dp.Field, f.Proto())
+	}
+	return dp
+}
+
+// MapOf adds a map entry message to dp and returns the map field, which still has to be added to dp.
+func MapOf(dp *descriptorpb.DescriptorProto, name string, number int32, key, value FieldSpec) FieldSpec {
+	entry := Message(mapEntryName(name), key, value)
+	entry.Options = &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)}
+	dp.NestedType = append(dp.NestedType, entry)
+	return Field(name, number, TypeMessage, entry.GetName()).List()
+}
+
+// mapEntryName returns the name protoc gives to the entry message of a map field.
+func mapEntryName(field string) string {
+	var b []byte
+	upper := true
+	for _, c := range []byte(field) {
+		switch {
+		case c == '_':
+			upper = true
+		case upper && c >= 'a' && c <= 'z':
+			b = append(b, c-'a'+'A')
+			upper = false
+		default:
+			b = append(b, c)
+			upper = false
+		}
+	}
+	return string(b) + "Entry"
+}
+
+// Enum describes an enum with the given values, numbered from zero.
+func Enum(name string, values ...string) *descriptorpb.EnumDescriptorProto {
+	ed := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+	for i, v := range values {
+		ed.Value = append(ed.Value, &descriptorpb.EnumValueDescriptorProto{Name: proto.String(v), Number: proto.Int32(int32(i))})
+	}
+	return ed
+}
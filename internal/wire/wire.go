@@ -0,0 +1,94 @@
+// Package wire holds the rules of the protobuf wire format and the path format of unknown fields
+// that unknownconnect, unknownconnecttest and their tests share.
+package wire
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Type returns the wire type values of the given kind are encoded with, unpacked.
+func Type(kind protoreflect.Kind) protowire.Type {
+	switch kind {
+	case protoreflect.BoolKind, protoreflect.EnumKind,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Uint32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Uint64Kind:
+		return protowire.VarintType
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return protowire.Fixed32Type
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return protowire.Fixed64Type
+	case protoreflect.GroupKind:
+		return protowire.StartGroupType
+	default:
+		return protowire.BytesType
+	}
+}
+
+// Accepts returns true if a value of the given wire type is parsed into the field. Values with the
+// wrong wire type end up in the unknown fields.
+func Accepts(fd protoreflect.FieldDescriptor, typ protowire.Type) bool {
+	want := Type(fd.Kind())
+	if typ == want {
+		return true
+	}
+	// repeated scalars are accepted both packed and unpacked
+	return typ == protowire.BytesType && fd.IsList() && want != protowire.StartGroupType
+}
+
+// IsClosedEnum returns true for proto2 enums, and for enums of editions files whose enum_type
+// feature is CLOSED. The feature set closest to the enum, on itself, the messages it is nested in
+// or the file, applies.
+func IsClosedEnum(ed protoreflect.EnumDescriptor) bool {
+	switch ed.ParentFile().Syntax() {
+	case protoreflect.Proto2:
+		return true
+	case protoreflect.Editions:
+		for d := protoreflect.Descriptor(ed); d != nil; d = d.Parent() {
+			if features := featuresOf(d); features != nil && features.EnumType != nil {
+				return features.GetEnumType() == descriptorpb.FeatureSet_CLOSED
+			}
+		}
+		// enums are open by default since edition 2023
+		return false
+	default:
+		return false
+	}
+}
+
+func featuresOf(d protoreflect.Descriptor) *descriptorpb.FeatureSet {
+	switch opts := d.Options().(type) {
+	case *descriptorpb.EnumOptions:
+		return opts.GetFeatures()
+	case *descriptorpb.MessageOptions:
+		return opts.GetFeatures()
+	case *descriptorpb.FileOptions:
+		return opts.GetFeatures()
+	default:
+		return nil
+	}
+}
+
+// JoinPath appends a field, and optionally a list index or map key, to an already formatted path,
+// like "users[0].tags[\"a\"]".
+func JoinPath(p, name, key string) string {
+	if p != "" {
+		p += "."
+	}
+	p += name
+	if key != "" {
+		p += "[" + key + "]"
+	}
+	return p
+}
+
+// MapKey formats a map key for JoinPath, quoting string keys.
+func MapKey(mk protoreflect.MapKey) string {
+	if s, ok := mk.Interface().(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return mk.String()
+}
@@ -0,0 +1,28 @@
+package wire_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestAccepts(t *testing.T) {
+	fields := (&new.NewUserRequest{}).ProtoReflect().Descriptor().Fields()
+	list := fields.ByName("primative_list")
+	assert.True(t, wire.Accepts(list, protowire.VarintType))
+	assert.True(t, wire.Accepts(list, protowire.BytesType), "packed")
+	assert.False(t, wire.Accepts(list, protowire.Fixed32Type))
+	user := fields.ByName("user")
+	assert.True(t, wire.Accepts(user, protowire.BytesType))
+	assert.False(t, wire.Accepts(user, protowire.VarintType))
+}
+
+func TestJoinPath(t *testing.T) {
+	p := wire.JoinPath("", "msg_map", wire.MapKey(protoreflect.ValueOfInt32(3).MapKey()))
+	p = wire.JoinPath(p, "tags", wire.MapKey(protoreflect.ValueOfString("a").MapKey()))
+	assert.Equal(t, `msg_map[3].tags["a"].name`, wire.JoinPath(p, "name", ""))
+}
@@ -0,0 +1,33 @@
+//go:build go1.23
+
+package unknownconnect
+
+import (
+	"iter"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnknownFields returns an iterator over every unknown field of msg and its nested messages.
+func UnknownFields(msg protoreflect.Message) iter.Seq[UnknownField] {
+	return func(yield func(UnknownField) bool) {
+		walkUnknownFields(msg, ScanLimits{}, func(p path, parent protoreflect.Message) bool {
+			for _, f := range appendUnknownFields(nil, p.String(), parent) {
+				if !yield(f) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// MessagesWithUnknownFields returns an iterator over msg and its nested messages that have unknown
+// fields, along with their paths.
+func MessagesWithUnknownFields(msg protoreflect.Message) iter.Seq2[string, protoreflect.Message] {
+	return func(yield func(string, protoreflect.Message) bool) {
+		walkUnknownFields(msg, ScanLimits{}, func(p path, parent protoreflect.Message) bool {
+			return yield(p.String(), parent)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+//go:build go1.23
+
+package unknownconnect_test
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+func TestUnknownFields(t *testing.T) {
+	withUnknown := func(name string) *new.User {
+		user := &new.User{Name: name}
+		user.ProtoReflect().SetUnknown(protopack.Message{
+			protopack.Tag{Number: 300, Type: protopack.VarintType}, protopack.Varint(1),
+			protopack.Tag{Number: 301, Type: protopack.BytesType}, protopack.String(name),
+		}.Marshal())
+		return user
+	}
+	req := &new.NewUserRequest{
+		User:    withUnknown("bob"),
+		MsgList: []*new.User{{Name: "alice"}, withUnknown("carol")},
+		MsgMap:  map[int32]*new.User{7: withUnknown("dave")},
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		var paths []string
+		var numbers []protowire.Number
+		for f := range unknownconnect.UnknownFields(req.ProtoReflect()) {
+			paths = append(paths, f.Path)
+			numbers = append(numbers, f.Number)
+		}
+		assert.ElementsMatch(t, []string{"user", "user", "msg_map[7]", "msg_map[7]", "msg_list[1]", "msg_list[1]"}, paths)
+		assert.ElementsMatch(t, []protowire.Number{300, 301, 300, 301, 300, 301}, numbers)
+	})
+	t.Run("fields break", func(t *testing.T) {
+		var count int
+		for range unknownconnect.UnknownFields(req.ProtoReflect()) {
+			count++
+			if count == 3 {
+				break
+			}
+		}
+		assert.Equal(t, 3, count)
+	})
+	t.Run("messages", func(t *testing.T) {
+		found := maps.Collect(unknownconnect.MessagesWithUnknownFields(req.ProtoReflect()))
+		assert.Equal(t, []string{"msg_list[1]", "msg_map[7]", "user"}, slices.Sorted(maps.Keys(found)))
+		assert.Equal(t, req.MsgList[1].ProtoReflect(), found["msg_list[1]"])
+	})
+	t.Run("messages break in map", func(t *testing.T) {
+		// the map is the only place with unknown fields, so breaking there has to stop the walk
+		req := &new.NewUserRequest{MsgMap: map[int32]*new.User{1: withUnknown("bob"), 2: withUnknown("carol")}}
+		var seen []protoreflect.Message
+		for _, msg := range unknownconnect.MessagesWithUnknownFields(req.ProtoReflect()) {
+			seen = append(seen, msg)
+			break
+		}
+		assert.Len(t, seen, 1)
+	})
+}
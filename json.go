@@ -0,0 +1,231 @@
+package unknownconnect
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"connectrpc.com/connect"
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var _ connect.Codec = (*JSONCodec)(nil)
+
+// JSONCodec is a connect.Codec for JSON that tolerates unknown keys instead of rejecting the
+// request. The keys it ignores are handed to interceptors created with WithJSONCodec, which report
+// them like any other unknown field. Pass the same codec to the client or handler with
+// connect.WithCodec and to the interceptor with WithJSONCodec.
+//
+// The codec is registered for the "json" content type. Handlers still use the default codec for
+// "json; charset=utf-8".
+type JSONCodec struct {
+	pending messageTable[[]UnknownField]
+}
+
+// NewJSONCodec creates a new JSONCodec.
+func NewJSONCodec() *JSONCodec {
+	return &JSONCodec{}
+}
+
+// Name implements connect.Codec.
+func (c *JSONCodec) Name() string {
+	return "json"
+}
+
+// Marshal implements connect.Codec.
+func (c *JSONCodec) Marshal(m any) ([]byte, error) {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return nil, errNotProto(m)
+	}
+	return protojson.Marshal(msg)
+}
+
+// Unmarshal implements connect.Codec.
+func (c *JSONCodec) Unmarshal(data []byte, m any) error {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errNotProto(m)
+	}
+	report, err := UnmarshalJSON(data, msg)
+	if err != nil {
+		return err
+	}
+	if !report.Empty() {
+		c.pending.put(msg, report.Fields)
+	}
+	return nil
+}
+
+// take returns and forgets the unknown keys found when msg was unmarshalled.
+func (c *JSONCodec) take(msg proto.Message) []UnknownField {
+	fields, _ := c.pending.take(msg)
+	return fields
+}
+
+func errNotProto(m any) error {
+	return fmt.Errorf("%T doesn't implement proto.Message", m)
+}
+
+// UnmarshalJSON unmarshals data into msg like protojson.Unmarshal, but ignores unknown keys instead
+// of failing. It returns a report of the keys that were ignored, in the order they appear in data,
+// with Name set to the key and Raw holding its JSON value.
+func UnmarshalJSON(data []byte, msg proto.Message) (*Report, error) {
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	w := &jsonWalker{dec: json.NewDecoder(bytes.NewReader(data)), data: data}
+	w.dec.UseNumber()
+	// protojson accepted the document, so the walk can only fail on what protojson tolerates and
+	// skips, and the keys found until then are still reported
+	_ = w.message(msg.ProtoReflect(), "")
+	return &Report{Fields: w.fields}, nil
+}
+
+// jsonWalker reads a JSON document once, token by token, alongside the message it was already
+// unmarshalled into, and collects the keys that don't match any field.
+type jsonWalker struct {
+	dec    *json.Decoder
+	data   []byte
+	fields []UnknownField
+}
+
+// message walks the JSON object of msg.
+func (w *jsonWalker) message(msg protoreflect.Message, p string) error {
+	md := msg.Descriptor()
+	if md.FullName().Parent() == "google.protobuf" {
+		// well-known types have their own JSON mappings
+		return w.skip()
+	}
+	if tok, err := w.dec.Token(); err != nil || tok != json.Delim('{') {
+		return err
+	}
+	for w.dec.More() {
+		tok, err := w.dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := tok.(string)
+		fd := md.Fields().ByJSONName(key)
+		if fd == nil {
+			fd = md.Fields().ByName(protoreflect.Name(key))
+		}
+		switch {
+		case fd == nil:
+			start := w.dec.InputOffset()
+			if err = w.skip(); err == nil {
+				// the value is copied, the codec may reuse data
+				raw := bytes.TrimLeft(w.data[start:w.dec.InputOffset()], " \t\r\n:")
+				w.fields = append(w.fields, UnknownField{Path: p, Parent: msg, Name: key, Kind: kindOf(md, key, 0, 0), Raw: append([]byte(nil), raw...)})
+			}
+		case !msg.Has(fd):
+			err = w.skip()
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			err = w.mapValues(msg.Get(fd).Map(), fd, p)
+		case fd.IsList() && fd.Message() != nil:
+			err = w.list(msg.Get(fd).List(), fd, p)
+		case !fd.IsMap() && !fd.IsList() && fd.Message() != nil:
+			err = w.message(msg.Get(fd).Message(), wire.JoinPath(p, string(fd.Name()), ""))
+		default:
+			err = w.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.dec.Token()
+	return err
+}
+
+// mapValues walks the JSON object of a map field with message values.
+func (w *jsonWalker) mapValues(m protoreflect.Map, fd protoreflect.FieldDescriptor, p string) error {
+	if tok, err := w.dec.Token(); err != nil || tok != json.Delim('{') {
+		return err
+	}
+	for w.dec.More() {
+		tok, err := w.dec.Token()
+		if err != nil {
+			return err
+		}
+		k, _ := tok.(string)
+		if mk, kerr := parseJSONMapKey(fd.MapKey(), k); kerr == nil && m.Has(mk) {
+			err = w.message(m.Get(mk).Message(), wire.JoinPath(p, string(fd.Name()), wire.MapKey(mk)))
+		} else {
+			err = w.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.dec.Token()
+	return err
+}
+
+// list walks the JSON array of a repeated message field.
+func (w *jsonWalker) list(list protoreflect.List, fd protoreflect.FieldDescriptor, p string) error {
+	if tok, err := w.dec.Token(); err != nil || tok != json.Delim('[') {
+		return err
+	}
+	for i := 0; w.dec.More(); i++ {
+		var err error
+		if i < list.Len() {
+			err = w.message(list.Get(i).Message(), wire.JoinPath(p, string(fd.Name()), strconv.Itoa(i)))
+		} else {
+			err = w.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.dec.Token()
+	return err
+}
+
+// skip reads past the next value.
+func (w *jsonWalker) skip() error {
+	depth := 0
+	for {
+		tok, err := w.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func parseJSONMapKey(fd protoreflect.FieldDescriptor, s string) (protoreflect.MapKey, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s).MapKey(), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(b).MapKey(), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)).MapKey(), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(n).MapKey(), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)).MapKey(), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(n).MapKey(), err
+	default:
+		return protoreflect.MapKey{}, errors.New("unsupported map key kind")
+	}
+}
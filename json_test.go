@@ -0,0 +1,123 @@
+package unknownconnect_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old/oldconnect"
+	"github.com/sudorandom/unknownconnect-go/internal/protobuild"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Run("without unknown keys", func(t *testing.T) {
+		req := &new.NewUserRequest{}
+		report, err := unknownconnect.UnmarshalJSON([]byte(`{"user": {"name": "bob", "email": "bob@example.com"}}`), req)
+		require.NoError(t, err)
+		assert.True(t, report.Empty())
+		assert.Equal(t, "bob@example.com", req.User.Email)
+	})
+	t.Run("with nested unknown keys", func(t *testing.T) {
+		req := &new.NewUserRequest{}
+		report, err := unknownconnect.UnmarshalJSON([]byte(`{
+			"user": {"name": "bob", "nickname": "bobby"},
+			"msg_list": [{"name": "alice"}, {"age": 42}],
+			"msgMap": {"7": {"name": "carol", "tags": ["a"]}},
+			"primativeMap": {"1": 2},
+			"extra": null
+		}`), req)
+		require.NoError(t, err)
+		assert.Equal(t, "bob", req.User.Name)
+		assert.Equal(t, "carol", req.MsgMap[7].Name)
+
+		type found struct{ path, name, raw string }
+		var got []found
+		for _, f := range report.Fields {
+			assert.Zero(t, f.Number)
+			got = append(got, found{f.Path, f.Name, string(f.Raw)})
+		}
+		assert.Equal(t, []found{
+			{"user", "nickname", `"bobby"`},
+			{"msg_list[1]", "age", "42"},
+			{"msg_map[7]", "tags", `["a"]`},
+			{"", "extra", "null"},
+		}, got)
+		assert.Equal(t, req.User.ProtoReflect(), report.Fields[0].Parent)
+	})
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := unknownconnect.UnmarshalJSON([]byte(`{"user": 1}`), &new.NewUserRequest{})
+		assert.Error(t, err)
+	})
+	t.Run("deeply nested", func(t *testing.T) {
+		fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+			Name:        proto.String("node.proto"),
+			Package:     proto.String("nodes"),
+			Syntax:      proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{protobuild.Message("Node", protobuild.Field("child", 1, protobuild.TypeMessage, "Node"))},
+		}, nil)
+		require.NoError(t, err)
+		// the document is read once, rather than once per level
+		const depth = 5000
+		data := strings.Repeat(`{"child": `, depth) + `{"extra": 1}` + strings.Repeat("}", depth)
+		report, err := unknownconnect.UnmarshalJSON([]byte(data), dynamicpb.NewMessage(fd.Messages().Get(0)))
+		require.NoError(t, err)
+		require.Len(t, report.Fields, 1)
+		assert.Equal(t, "extra", report.Fields[0].Name)
+		assert.Equal(t, strings.Repeat("child.", depth-1)+"child", report.Fields[0].Path)
+	})
+}
+
+func TestJSONCodec(t *testing.T) {
+	codec := unknownconnect.NewJSONCodec()
+	var reports []*unknownconnect.Report
+	var calledCount int
+	interceptor := unknownconnect.NewInterceptor(
+		unknownconnect.WithJSONCodec(codec),
+		unknownconnect.WithCallback(func(context.Context, connect.Spec, proto.Message) error {
+			calledCount++
+			return nil
+		}),
+		unknownconnect.WithReportCallback(func(_ context.Context, _ connect.Spec, report *unknownconnect.Report) error {
+			reports = append(reports, report)
+			return nil
+		}),
+	)
+	_, handler := oldconnect.NewUserManagementHandler(
+		oldconnect.UnimplementedUserManagementHandler{},
+		connect.WithCodec(codec),
+		connect.WithInterceptors(interceptor),
+	)
+
+	req, err := http.NewRequest("POST", oldconnect.UserManagementNewUserProcedure, strings.NewReader(`{"user": {"name": "bob", "email": "bob@example.com"}}`))
+	require.NoError(t, err)
+	req.Header.Add("Content-Type", "application/json")
+	resp, err := NewLocalClient(handler).Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.Equal(t, 1, calledCount)
+	require.Len(t, reports, 1)
+	require.Len(t, reports[0].Fields, 1)
+	assert.Equal(t, "user", reports[0].Fields[0].Path)
+	assert.Equal(t, "email", reports[0].Fields[0].Name)
+	assert.Equal(t, `"bob@example.com"`, string(reports[0].Fields[0].Raw))
+
+	t.Run("marshal", func(t *testing.T) {
+		b, err := codec.Marshal(&old.User{Name: "bob"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "bob"}`, string(b))
+		_, err = codec.Marshal("bob")
+		assert.Error(t, err)
+	})
+}
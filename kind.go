@@ -0,0 +1,137 @@
+package unknownconnect
+
+import (
+	"strings"
+
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnknownKind classifies an unknown field by what it says about the peer that sent it.
+type UnknownKind int
+
+const (
+	// KindNew is a field the local schema has no trace of, most likely added to the schema after
+	// this version was built: the peer is newer.
+	KindNew UnknownKind = iota
+	// KindReserved is a field whose number, or name in JSON, is reserved in the local schema. JSON keys
+	// match a reserved name as written or in its camel case JSON form. The field was removed and the
+	// peer is older, a stale client or server that still sends it.
+	KindReserved
+	// KindUnregisteredExtension is a field whose number is in one of the extension ranges of the
+	// local message, for an extension that isn't registered, see WithExtensionResolver.
+	KindUnregisteredExtension
+	// KindEnumDrift is a value of a closed enum field that the local enum doesn't define. Closed
+	// enums, like those of proto2 files, keep such values in the unknown fields, so the field is
+	// known but the enum of the peer has more values. The Go runtime treats closed enums like open
+	// ones and keeps the values as set instead, so reports also list set values of closed enum
+	// fields, list elements and map values that the enum doesn't define, encoded on their own in
+	// Raw. Those aren't unknown fields as far as the message is concerned: dropping unknown fields
+	// keeps them, and MessageHasUnknownFields and UnknownFields ignore them.
+	KindEnumDrift
+	// KindTypeMismatch is a field whose number the local message has, but that was kept in the unknown
+	// fields rather than parsed into the field, mostly because its value has a wire type the field
+	// can't hold, like a string sent for an int32. The type of the field changed between versions of
+	// the schema, which is a breaking change whichever peer is newer.
+	KindTypeMismatch
+)
+
+func (k UnknownKind) String() string {
+	switch k {
+	case KindNew:
+		return "new"
+	case KindReserved:
+		return "reserved"
+	case KindUnregisteredExtension:
+		return "unregistered_extension"
+	case KindEnumDrift:
+		return "enum_drift"
+	case KindTypeMismatch:
+		return "type_mismatch"
+	default:
+		return "unknown"
+	}
+}
+
+// kindOf classifies an unknown field of a message of type md, found in JSON under name or in the
+// wire format with number num and wire type typ.
+func kindOf(md protoreflect.MessageDescriptor, name string, num protowire.Number, typ protowire.Type) UnknownKind {
+	if name != "" {
+		if isReservedName(md.ReservedNames(), name) {
+			return KindReserved
+		}
+		return KindNew
+	}
+	switch {
+	case md.ReservedRanges().Has(num):
+		return KindReserved
+	case md.ExtensionRanges().Has(num):
+		return KindUnregisteredExtension
+	case isEnumDrift(md.Fields().ByNumber(num), typ):
+		return KindEnumDrift
+	case md.Fields().ByNumber(num) != nil:
+		return KindTypeMismatch
+	default:
+		return KindNew
+	}
+}
+
+// isReservedName returns true if the JSON key is one of the reserved names, or the JSON name a field
+// with one of them would have had.
+func isReservedName(names protoreflect.Names, key string) bool {
+	for i := 0; i < names.Len(); i++ {
+		name := string(names.Get(i))
+		if key == name || key == jsonName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonName returns the default JSON name of a field, the way protoc derives it: underscores are
+// removed and the letter following each is capitalized.
+func jsonName(name string) string {
+	var sb strings.Builder
+	upper := false
+	for _, c := range name {
+		switch {
+		case c == '_':
+			upper = true
+		case upper && 'a' <= c && c <= 'z':
+			sb.WriteRune(c - 'a' + 'A')
+			upper = false
+		default:
+			sb.WriteRune(c)
+			upper = false
+		}
+	}
+	return sb.String()
+}
+
+// isEnumDrift returns true if a field with the given wire type in the unknown fields is a value
+// of the closed enum field fd: a single value or packed values, or a map entry with an enum value.
+func isEnumDrift(fd protoreflect.FieldDescriptor, typ protowire.Type) bool {
+	switch {
+	case fd == nil:
+		return false
+	case fd.IsMap():
+		ed := fd.MapValue().Enum()
+		return ed != nil && wire.IsClosedEnum(ed) && typ == protowire.BytesType
+	case fd.Enum() != nil:
+		return wire.IsClosedEnum(fd.Enum()) && (typ == protowire.VarintType || (typ == protowire.BytesType && fd.IsList()))
+	default:
+		return false
+	}
+}
+
+// OfKind returns a report of the fields of the given kind only.
+func (r *Report) OfKind(kind UnknownKind) *Report {
+	filtered := &Report{Truncated: r.Truncated}
+	for _, f := range r.Fields {
+		if f.Kind == kind {
+			filtered.Fields = append(filtered.Fields, f)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,86 @@
+package unknownconnect_test
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/profile"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+func TestUnknownKind(t *testing.T) {
+	msg := &profile.Profile{}
+	require.NoError(t, proto.Unmarshal(protopack.Message{
+		protopack.Tag{Number: 1, Type: protopack.BytesType}, protopack.String("bob"),
+		protopack.Tag{Number: 2, Type: protopack.BytesType}, protopack.String("bob@example.com"),
+		protopack.Tag{Number: 7, Type: protopack.VarintType}, protopack.Varint(1),
+		protopack.Tag{Number: 8, Type: protopack.VarintType}, protopack.Varint(1),
+		// name used to be a number
+		protopack.Tag{Number: 1, Type: protopack.VarintType}, protopack.Varint(1),
+	}.Marshal(), msg))
+
+	kinds := map[protowire.Number]unknownconnect.UnknownKind{}
+	for _, f := range unknownconnect.NewReport(msg.ProtoReflect()).Fields {
+		kinds[f.Number] = f.Kind
+	}
+	assert.Equal(t, map[protowire.Number]unknownconnect.UnknownKind{
+		1: unknownconnect.KindTypeMismatch,
+		2: unknownconnect.KindReserved,
+		7: unknownconnect.KindReserved,
+		8: unknownconnect.KindNew,
+	}, kinds)
+
+	t.Run("json", func(t *testing.T) {
+		report, err := unknownconnect.UnmarshalJSON([]byte(`{"name": "bob", "email": "bob@example.com", "phoneNumber": "555", "phone_number": "555", "nickname": "b"}`), &profile.Profile{})
+		require.NoError(t, err)
+		kinds := map[string]unknownconnect.UnknownKind{}
+		for _, f := range report.Fields {
+			kinds[f.Name] = f.Kind
+		}
+		assert.Equal(t, map[string]unknownconnect.UnknownKind{
+			"email":        unknownconnect.KindReserved,
+			"phoneNumber":  unknownconnect.KindReserved,
+			"phone_number": unknownconnect.KindReserved,
+			"nickname":     unknownconnect.KindNew,
+		}, kinds)
+	})
+
+	t.Run("interceptor", func(t *testing.T) {
+		var stale []*unknownconnect.Report
+		byKind := map[string]float64{}
+		interceptor := unknownconnect.NewInterceptor(
+			unknownconnect.WithStaleCallback(func(ctx context.Context, s connect.Spec, r *unknownconnect.Report) error {
+				stale = append(stale, r)
+				return nil
+			}),
+			unknownconnect.WithMetrics(unknownconnect.MetricsFunc(func(_ context.Context, m unknownconnect.Measurement) {
+				if m.Name == unknownconnect.MetricUnknownFieldsByKind {
+					byKind[m.Label] = m.Value
+				}
+			})),
+		)
+		unary := interceptor.WrapUnary(connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			return nil, nil
+		}))
+		_, err := unary(context.Background(), connect.NewRequest(msg))
+		require.NoError(t, err)
+		require.Len(t, stale, 1)
+		require.Len(t, stale[0].Fields, 2)
+		assert.Equal(t, protowire.Number(2), stale[0].Fields[0].Number)
+		assert.Equal(t, protowire.Number(7), stale[0].Fields[1].Number)
+		assert.Equal(t, map[string]float64{"new": 1, "reserved": 2, "type_mismatch": 1}, byKind)
+
+		// only new fields don't make a stale client
+		fresh := &profile.Profile{}
+		fresh.ProtoReflect().SetUnknown(protopack.Message{protopack.Tag{Number: 8, Type: protopack.VarintType}, protopack.Varint(1)}.Marshal())
+		_, err = unary(context.Background(), connect.NewRequest(fresh))
+		require.NoError(t, err)
+		assert.Len(t, stale, 1)
+	})
+}
@@ -0,0 +1,161 @@
+package unknownconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrScanLimitExceeded is returned when scanning a message hits one of its ScanLimits and the limits
+// are configured with LimitReject.
+var ErrScanLimitExceeded = errors.New("unknown field scan limit exceeded")
+
+// ErrUnknownLimitExceeded is returned when a peer sends more unknown data than its UnknownLimits
+// allow.
+var ErrUnknownLimitExceeded = errors.New("too much unknown data")
+
+// LimitAction decides what happens when a scan hits one of its ScanLimits.
+type LimitAction int
+
+const (
+	// LimitStop stops the scan, treating the rest of the message as if it had no unknown fields.
+	LimitStop LimitAction = iota
+	// LimitTruncate stops the scan and marks the report as truncated.
+	LimitTruncate
+	// LimitReject fails with ErrScanLimitExceeded. The interceptor returns it to the peer as a
+	// connect.CodeResourceExhausted error.
+	LimitReject
+)
+
+// ScanLimits bounds the work done scanning a single message for unknown fields, so a peer can't use
+// deeply nested or enormous messages to exhaust the stack or CPU. A zero value means no limit.
+type ScanLimits struct {
+	// MaxDepth is the deepest level of nested messages that is scanned. The scanned message itself
+	// is at depth zero.
+	MaxDepth int
+	// MaxNodes is the number of messages, including the scanned message itself, that are visited.
+	MaxNodes int
+	// MaxUnknownBytes is the total size of the unknown fields that are examined.
+	MaxUnknownBytes int
+	// OnLimit decides what happens when one of the limits is hit.
+	OnLimit LimitAction
+}
+
+// NewLimitedReport is like NewReport but stops scanning when one of the given limits is hit. The
+// report is marked as truncated with LimitTruncate and ErrScanLimitExceeded is returned with
+// LimitReject.
+func NewLimitedReport(msg protoreflect.Message, limits ScanLimits) (*Report, error) {
+	r := &Report{}
+	exceeded := walkReportedFields(msg, limits, func(p path, msg protoreflect.Message) bool {
+		r.Fields = appendUnknownFields(r.Fields, p.String(), msg)
+		r.Fields = appendSetEnumDrift(r.Fields, p.String(), msg)
+		return true
+	})
+	if exceeded {
+		switch limits.OnLimit {
+		case LimitTruncate:
+			r.Truncated = true
+		case LimitReject:
+			return nil, ErrScanLimitExceeded
+		}
+	}
+	return r, nil
+}
+
+// hasUnknownFields is like MessageHasUnknownFields but stops scanning when one of the given limits
+// is hit, and also counts the closed enum values reports list as KindEnumDrift. With LimitReject,
+// it keeps scanning past the first unknown field so that messages over the limits are always
+// rejected.
+func hasUnknownFields(msg protoreflect.Message, limits ScanLimits) (bool, error) {
+	var hasUnknown bool
+	exceeded := walkReportedFields(msg, limits, func(_ path, _ protoreflect.Message) bool {
+		hasUnknown = true
+		return limits.OnLimit == LimitReject
+	})
+	if exceeded && limits.OnLimit == LimitReject {
+		return false, ErrScanLimitExceeded
+	}
+	return hasUnknown, nil
+}
+
+// UnknownLimits caps the amount of unknown data a peer may send. Unknown fields are opaque bytes
+// that are kept and often sent on again, so they can be used to smuggle data or to make a service
+// hold on to a lot of memory. A zero value means no limit.
+type UnknownLimits struct {
+	// MaxFieldsPerMessage is the number of unknown fields allowed in a single message.
+	MaxFieldsPerMessage int
+	// MaxBytesPerMessage is the total size of unknown fields allowed in a single message.
+	MaxBytesPerMessage int
+	// MaxFieldsPerStream is the number of unknown fields allowed across all messages received on a
+	// stream. For unary RPCs, this is the same as MaxFieldsPerMessage.
+	MaxFieldsPerStream int
+	// MaxBytesPerStream is the total size of unknown fields allowed across all messages received on
+	// a stream. For unary RPCs, this is the same as MaxBytesPerMessage.
+	MaxBytesPerStream int
+	// Code is the code of the error returned when a limit is exceeded. It defaults to
+	// connect.CodeResourceExhausted.
+	Code connect.Code
+}
+
+func (l UnknownLimits) enabled() bool {
+	return l.MaxFieldsPerMessage > 0 || l.MaxBytesPerMessage > 0 || l.MaxFieldsPerStream > 0 || l.MaxBytesPerStream > 0
+}
+
+// streamUsage keeps track of the unknown data received on a single stream.
+type streamUsage struct {
+	fields int
+	bytes  int
+}
+
+// checkUnknownLimits adds the report to the stream's usage, records how close it gets to the
+// limits and returns an error if any of them is exceeded.
+func (o *interceptorOpts) checkUnknownLimits(ctx context.Context, spec connect.Spec, report *Report, usage *streamUsage) error {
+	fields, size := len(report.Fields), report.Size()
+	o.record(ctx, spec, MetricUnknownFields, "", float64(fields))
+	o.record(ctx, spec, MetricUnknownBytes, "", float64(size))
+	o.recordKinds(ctx, spec, report)
+	return o.enforceUnknownLimits(usage, fields, size, func(limit string, used float64) {
+		o.record(ctx, spec, MetricLimitUsage, limit, used)
+	})
+}
+
+// enforceUnknownLimits adds fields and size to the stream's usage and returns an error if any of
+// the limits is exceeded. If record is not nil, it is called with how close usage is to each limit.
+func (o *interceptorOpts) enforceUnknownLimits(usage *streamUsage, fields, size int, record func(limit string, used float64)) error {
+	usage.fields += fields
+	usage.bytes += size
+
+	var exceeded []string
+	for _, check := range []struct {
+		name  string
+		used  int
+		limit int
+	}{
+		{name: "fields_per_message", used: fields, limit: o.unknownLimits.MaxFieldsPerMessage},
+		{name: "bytes_per_message", used: size, limit: o.unknownLimits.MaxBytesPerMessage},
+		{name: "fields_per_stream", used: usage.fields, limit: o.unknownLimits.MaxFieldsPerStream},
+		{name: "bytes_per_stream", used: usage.bytes, limit: o.unknownLimits.MaxBytesPerStream},
+	} {
+		if check.limit <= 0 {
+			continue
+		}
+		if record != nil {
+			record(check.name, float64(check.used)/float64(check.limit))
+		}
+		if check.used > check.limit {
+			exceeded = append(exceeded, fmt.Sprintf("%s: %d > %d", check.name, check.used, check.limit))
+		}
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	code := o.unknownLimits.Code
+	if code == 0 {
+		code = connect.CodeResourceExhausted
+	}
+	return connect.NewError(code, fmt.Errorf("%w (%s)", ErrUnknownLimitExceeded, strings.Join(exceeded, ", ")))
+}
@@ -0,0 +1,150 @@
+package unknownconnect
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/account"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestNewLimitedReport(t *testing.T) {
+	md := benchNodeDescriptor(t)
+	child := md.Fields().ByName("child")
+	// a chain of 5 nodes, each with 2 bytes of unknown fields
+	root := deepBenchNode(md, 5)
+	for msg := protoreflect.Message(root); ; msg = msg.Get(child).Message() {
+		msg.SetUnknown(protoreflect.RawFields{8, 1})
+		if !msg.Has(child) {
+			break
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		limits ScanLimits
+		fields int
+	}{
+		{name: "no limits", limits: ScanLimits{}, fields: 5},
+		{name: "within limits", limits: ScanLimits{MaxDepth: 4, MaxNodes: 5, MaxUnknownBytes: 10}, fields: 5},
+		{name: "depth", limits: ScanLimits{MaxDepth: 2}, fields: 3},
+		{name: "nodes", limits: ScanLimits{MaxNodes: 2}, fields: 2},
+		{name: "unknown bytes", limits: ScanLimits{MaxUnknownBytes: 7}, fields: 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			exceeded := tc.fields < 5
+
+			report, err := NewLimitedReport(root, tc.limits)
+			require.NoError(t, err)
+			assert.Len(t, report.Fields, tc.fields)
+			assert.False(t, report.Truncated)
+
+			tc.limits.OnLimit = LimitTruncate
+			report, err = NewLimitedReport(root, tc.limits)
+			require.NoError(t, err)
+			assert.Len(t, report.Fields, tc.fields)
+			assert.Equal(t, exceeded, report.Truncated)
+
+			tc.limits.OnLimit = LimitReject
+			_, err = NewLimitedReport(root, tc.limits)
+			if exceeded {
+				assert.ErrorIs(t, err, ErrScanLimitExceeded)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestInterceptorScanLimits(t *testing.T) {
+	md := benchNodeDescriptor(t)
+	root := deepBenchNode(md, 5)
+	var leaf protoreflect.Message = root
+	for leaf.Has(md.Fields().ByName("child")) {
+		leaf = leaf.Get(md.Fields().ByName("child")).Message()
+	}
+	leaf.SetUnknown(protoreflect.RawFields{8, 1})
+
+	var received *dynamicpb.Message
+	inspect := func(opts ...option) error {
+		conn := &stubHandlerConn{msg: root}
+		handler := NewInterceptor(opts...).WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			received = dynamicpb.NewMessage(md)
+			return conn.Receive(received)
+		})
+		return handler(context.Background(), conn)
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		var called bool
+		err := inspect(
+			WithScanLimits(ScanLimits{MaxDepth: 2}),
+			WithCallback(func(context.Context, connect.Spec, proto.Message) error {
+				called = true
+				return nil
+			}))
+		require.NoError(t, err)
+		assert.False(t, called)
+	})
+	t.Run("truncate", func(t *testing.T) {
+		var report *Report
+		err := inspect(
+			WithScanLimits(ScanLimits{MaxDepth: 2, OnLimit: LimitTruncate}),
+			WithReportCallback(func(_ context.Context, _ connect.Spec, r *Report) error {
+				report = r
+				return nil
+			}))
+		require.NoError(t, err)
+		require.NotNil(t, report)
+		assert.True(t, report.Truncated)
+		assert.Empty(t, report.Fields)
+	})
+	t.Run("reject", func(t *testing.T) {
+		err := inspect(WithScanLimits(ScanLimits{MaxNodes: 3, OnLimit: LimitReject}))
+		assert.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+		assert.True(t, errors.Is(err, ErrScanLimitExceeded))
+	})
+	t.Run("drop past limits", func(t *testing.T) {
+		require.NoError(t, inspect(WithDrop(), WithScanLimits(ScanLimits{MaxDepth: 2})))
+		assert.False(t, MessageHasUnknownFields(received))
+		require.NoError(t, inspect(WithDrop(), WithScanLimits(ScanLimits{MaxNodes: 1, OnLimit: LimitTruncate})))
+		assert.False(t, MessageHasUnknownFields(received))
+	})
+}
+
+// stubHandlerConn receives a copy of msg.
+type stubHandlerConn struct {
+	connect.StreamingHandlerConn
+	msg proto.Message
+}
+
+func (c *stubHandlerConn) Spec() connect.Spec {
+	return connect.Spec{StreamType: connect.StreamTypeClient}
+}
+
+func (c *stubHandlerConn) Receive(msg any) error {
+	proto.Merge(msg.(proto.Message), c.msg)
+	return nil
+}
+
+func TestFindDeprecatedLimits(t *testing.T) {
+	msg := &account.Account{LegacyId: "b-1", Linked: []*account.Account{{Linked: []*account.Account{{LegacyId: "b-3"}}}}}
+	for i := 0; i < 100; i++ {
+		msg.Notes = append(msg.Notes, &account.Note{Text: "note"})
+	}
+
+	usages, exceeded := findDeprecated(msg.ProtoReflect(), ScanLimits{MaxNodes: 3})
+	assert.False(t, exceeded, "notes can't hold anything deprecated, so they aren't visited")
+	assert.Len(t, usages, 2)
+
+	usages, exceeded = findDeprecated(msg.ProtoReflect(), ScanLimits{MaxDepth: 1})
+	assert.True(t, exceeded)
+	assert.Len(t, usages, 1)
+}
@@ -0,0 +1,94 @@
+package unknownconnect
+
+import (
+	"context"
+	"sort"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// MetricUnknownFields is the number of unknown fields in a message that has any.
+	MetricUnknownFields = "unknown_fields"
+	// MetricUnknownBytes is the total size of the unknown fields in a message that has any.
+	MetricUnknownBytes = "unknown_bytes"
+	// MetricLimitUsage is how much of one of the UnknownLimits a message or stream used, as a
+	// fraction where 1 means the limit was reached. The label is the name of the limit, like
+	// "bytes_per_stream".
+	MetricLimitUsage = "unknown_limit_usage"
+	// MetricUnknownFieldsByKind is the number of unknown fields of one kind in a message that has
+	// any. The label is the UnknownKind, like "reserved" or "enum_drift".
+	MetricUnknownFieldsByKind = "unknown_fields_by_kind"
+	// MetricDeprecatedFields is the number of times a deprecated field or enum value is set in a
+	// message, see WithDeprecated. The label is the full name of the field or enum value.
+	MetricDeprecatedFields = "deprecated_fields"
+)
+
+// Measurement is a single value recorded by the interceptor.
+type Measurement struct {
+	// Name is one of the Metric constants.
+	Name string
+	// Spec is the spec of the RPC the measurement was taken for.
+	Spec connect.Spec
+	// Label further qualifies the measurement. What it holds depends on the metric.
+	Label string
+	Value float64
+}
+
+// Metrics receives measurements from the interceptor, typically to forward them to a metrics
+// system like Prometheus or OpenTelemetry.
+type Metrics interface {
+	Record(ctx context.Context, m Measurement)
+}
+
+// MetricsFunc is a function that implements Metrics.
+type MetricsFunc func(ctx context.Context, m Measurement)
+
+// Record implements Metrics.
+func (f MetricsFunc) Record(ctx context.Context, m Measurement) {
+	f(ctx, m)
+}
+
+// recordKinds records how many unknown fields of each kind the report has.
+func (o *interceptorOpts) recordKinds(ctx context.Context, spec connect.Spec, report *Report) {
+	if o.metrics == nil {
+		return
+	}
+	var kinds []UnknownKind
+	counts := map[UnknownKind]int{}
+	for _, f := range report.Fields {
+		if counts[f.Kind] == 0 {
+			kinds = append(kinds, f.Kind)
+		}
+		counts[f.Kind]++
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	for _, kind := range kinds {
+		o.record(ctx, spec, MetricUnknownFieldsByKind, kind.String(), float64(counts[kind]))
+	}
+}
+
+// recordDeprecated records how many times each deprecated field or enum value is used.
+func (o *interceptorOpts) recordDeprecated(ctx context.Context, spec connect.Spec, usages []DeprecatedUsage) {
+	if o.metrics == nil {
+		return
+	}
+	var names []protoreflect.FullName
+	counts := map[protoreflect.FullName]int{}
+	for _, u := range usages {
+		if counts[u.Name()] == 0 {
+			names = append(names, u.Name())
+		}
+		counts[u.Name()]++
+	}
+	for _, name := range names {
+		o.record(ctx, spec, MetricDeprecatedFields, string(name), float64(counts[name]))
+	}
+}
+
+func (o *interceptorOpts) record(ctx context.Context, spec connect.Spec, name, label string, value float64) {
+	if o.metrics != nil {
+		o.metrics.Record(ctx, Measurement{Name: name, Spec: spec, Label: label, Value: value})
+	}
+}
@@ -1,5 +1,7 @@
 package unknownconnect
 
+import "google.golang.org/protobuf/reflect/protoregistry"
+
 type option func(opts *interceptorOpts)
 
 func WithDrop() option {
@@ -8,8 +10,135 @@ func WithDrop() option {
 	}
 }
 
+// WithDropRules makes the interceptor drop only the unknown fields selected by rules, after they
+// have been inspected, instead of all of them like WithDrop.
+func WithDropRules(rules DropRules) option {
+	return func(opts *interceptorOpts) {
+		opts.drop = true
+		opts.dropRules = &rules
+	}
+}
+
 func WithCallback(callback UnknownCallback) option {
 	return func(opts *interceptorOpts) {
 		opts.callbacks = append(opts.callbacks, callback)
 	}
 }
+
+// WithReportCallback registers a callback that gets a report of every unknown field whenever a
+// message has any.
+func WithReportCallback(callback ReportCallback) option {
+	return func(opts *interceptorOpts) {
+		opts.reportCallbacks = append(opts.reportCallbacks, callback)
+	}
+}
+
+// WithStaleCallback registers a callback that gets a report of the unknown fields whose numbers or
+// names are reserved in the local schema, whenever a message has any. Those fields were removed, so
+// they come from peers built with an older version of the schema, which have to be upgraded.
+func WithStaleCallback(callback ReportCallback) option {
+	return func(opts *interceptorOpts) {
+		opts.staleCallbacks = append(opts.staleCallbacks, callback)
+	}
+}
+
+// WithExtensionResolver parses the unknown fields of received messages that are extensions known to
+// resolver, so that the unknown fields of messages they hold are found too. Like the extensions
+// registered globally, they are no longer unknown. Messages with malformed extensions are rejected.
+// Extensions are resolved within the limits of WithScanLimits, and only in the messages picked by
+// WithSampling, unless unknown fields are dropped too.
+func WithExtensionResolver(resolver protoregistry.ExtensionTypeResolver) option {
+	return func(opts *interceptorOpts) {
+		opts.extensionResolver = resolver
+	}
+}
+
+// WithJSONCodec makes the interceptor report the unknown JSON keys the given codec ignored. The
+// codec also has to be given to the client or handler with connect.WithCodec.
+func WithJSONCodec(codec *JSONCodec) option {
+	return func(opts *interceptorOpts) {
+		opts.jsonCodec = codec
+	}
+}
+
+// WithProtoCodec makes the interceptor trust the given codec about which messages have no unknown
+// fields, skipping the walk over those. The codec also has to be given to the client or handler
+// with connect.WithCodec.
+func WithProtoCodec(codec *ProtoCodec) option {
+	return func(opts *interceptorOpts) {
+		opts.protoCodec = codec
+	}
+}
+
+// WithScanLimits bounds the work done scanning each message for unknown fields for callbacks,
+// reports and metrics. Dropping unknown fields, with WithDrop or WithDropRules, ignores the limits so
+// that none are kept past them.
+func WithScanLimits(limits ScanLimits) option {
+	return func(opts *interceptorOpts) {
+		opts.limits = limits
+	}
+}
+
+// WithUnknownLimits caps the amount of unknown data a peer may send in each message and on each
+// stream. Messages over the limits are rejected with an error.
+func WithUnknownLimits(limits UnknownLimits) option {
+	return func(opts *interceptorOpts) {
+		opts.unknownLimits = limits
+	}
+}
+
+// WithMetrics records measurements about unknown fields, like their number and size and how close
+// they get to the limits set with WithUnknownLimits.
+func WithMetrics(metrics Metrics) option {
+	return func(opts *interceptorOpts) {
+		opts.metrics = metrics
+	}
+}
+
+// WithSampling only inspects some of the messages, as configured. Messages that aren't sampled
+// aren't reported, but WithDrop still applies to them and they are still checked against
+// WithUnknownLimits, which means scanning them when those are set.
+func WithSampling(sampling Sampling) option {
+	return func(opts *interceptorOpts) {
+		opts.sampler = newSampler(sampling)
+	}
+}
+
+// WithRedaction hides the contents of unknown fields from callbacks. Reports list the length and an
+// HMAC of each unknown field instead of its contents, and callbacks registered with WithCallback get
+// a copy of the message without unknown fields. Procedures listed in the redaction still see
+// everything.
+func WithRedaction(redaction Redaction) option {
+	return func(opts *interceptorOpts) {
+		opts.redactor = newRedactor(redaction)
+	}
+}
+
+// WithCapture saves every message with unknown fields that the capturer's sampling allows. With
+// WithRedaction, only messages of procedures that may see unknown fields as is are captured.
+func WithCapture(capturer *Capturer) option {
+	return func(opts *interceptorOpts) {
+		opts.capturer = capturer
+	}
+}
+
+// WithDeprecated also looks for deprecated fields and enum values set in received messages, to know
+// when no peer uses them anymore. They are listed in the Report.Deprecated of reports passed to
+// callbacks registered with WithReportCallback, which are then called for messages without unknown
+// fields too, and counted by the MetricDeprecatedFields metric. The search is bound by the depth and
+// node limits of WithScanLimits, and skips nested messages whose type can't hold anything
+// deprecated.
+func WithDeprecated() option {
+	return func(opts *interceptorOpts) {
+		opts.deprecated = true
+	}
+}
+
+// WithChaos injects random unknown fields into a fraction of the messages the client or handler
+// sends, for testing that peers tolerate them. The messages are modified in place. Never use it in
+// production.
+func WithChaos(chaos *Chaos) option {
+	return func(opts *interceptorOpts) {
+		opts.chaos = chaos
+	}
+}
@@ -0,0 +1,89 @@
+package unknownconnect
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Redaction configures how unknown fields are hidden from callbacks. Unknown fields may contain data,
+// like personal information, that a service never agreed to handle, so they shouldn't end up in
+// logs as is.
+type Redaction struct {
+	// Key is the key of the HMAC that replaces the contents of unknown fields. The same contents
+	// always hash to the same value under the same key, so fields can still be told apart and
+	// correlated. If empty, a random key is generated.
+	Key []byte
+	// RawProcedures lists procedures, like "/greet.v1.GreetService/Greet", whose unknown fields are
+	// passed to callbacks as is.
+	RawProcedures []string
+}
+
+// Redact returns a copy of the report in which the contents of every unknown field are replaced by
+// their length and the HMAC-SHA256 of their contents under key. The Parent of every field is removed
+// as it still holds the unknown fields.
+func (r *Report) Redact(key []byte) *Report {
+	redacted := &Report{Fields: make([]UnknownField, len(r.Fields)), Truncated: r.Truncated, Deprecated: r.Deprecated}
+	for i, f := range r.Fields {
+		if f.HMAC == nil {
+			mac := hmac.New(sha256.New, key)
+			mac.Write(f.Raw)
+			f.HMAC, f.Length = mac.Sum(nil), len(f.Raw)
+		}
+		f.Parent, f.Raw = nil, nil
+		redacted.Fields[i] = f
+	}
+	return redacted
+}
+
+// redactedString describes the contents of a redacted field.
+func (f UnknownField) redactedString() string {
+	return fmt.Sprintf("<redacted %d bytes, hmac %x>", f.Length, f.HMAC[:8])
+}
+
+// redactor applies a Redaction to what is passed to callbacks.
+type redactor struct {
+	key []byte
+	raw map[string]bool
+}
+
+func newRedactor(config Redaction) *redactor {
+	r := &redactor{key: config.Key, raw: map[string]bool{}}
+	if len(r.key) == 0 {
+		r.key = make([]byte, sha256.Size)
+		if _, err := rand.Read(r.key); err != nil {
+			panic(fmt.Sprintf("unknownconnect: generating redaction key: %v", err))
+		}
+	}
+	for _, procedure := range config.RawProcedures {
+		r.raw[procedure] = true
+	}
+	return r
+}
+
+// allowsRaw returns true if callbacks for the procedure may see the contents of unknown fields.
+func (r *redactor) allowsRaw(procedure string) bool {
+	return r == nil || r.raw[procedure]
+}
+
+// redactMessage returns the message to pass to callbacks for the procedure: a copy without unknown
+// fields, unless the procedure is allowed to see them.
+func (r *redactor) redactMessage(procedure string, msg proto.Message) proto.Message {
+	if r.allowsRaw(procedure) {
+		return msg
+	}
+	msg = proto.Clone(msg)
+	DropUnknownFields(msg.ProtoReflect())
+	return msg
+}
+
+// redactReport returns the report to pass to callbacks for the procedure.
+func (r *redactor) redactReport(procedure string, report *Report) *Report {
+	if report == nil || r.allowsRaw(procedure) {
+		return report
+	}
+	return report.Redact(r.key)
+}
@@ -0,0 +1,95 @@
+package unknownconnect_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+func TestReportRedact(t *testing.T) {
+	user := &new.User{Name: "bob"}
+	raw := protopack.Message{protopack.Tag{Number: 300, Type: protopack.BytesType}, protopack.String("123-45-6789")}.Marshal()
+	user.ProtoReflect().SetUnknown(raw)
+	report := unknownconnect.NewReport(user.ProtoReflect())
+
+	redacted := report.Redact([]byte("key"))
+	require.Len(t, redacted.Fields, 1)
+	f := redacted.Fields[0]
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write(raw)
+	assert.Equal(t, mac.Sum(nil), f.HMAC)
+	assert.Equal(t, len(raw), f.Length)
+	assert.Nil(t, f.Raw)
+	assert.Nil(t, f.Parent)
+	assert.Equal(t, report.Size(), redacted.Size())
+	assert.NotContains(t, f.String(), "123-45-6789")
+	assert.Equal(t, raw, report.Fields[0].Raw, "the original report is left alone")
+	assert.Equal(t, f.HMAC, redacted.Redact([]byte("other")).Fields[0].HMAC, "redacting twice keeps the first hash")
+}
+
+func TestInterceptorRedaction(t *testing.T) {
+	user := &new.User{Name: "bob"}
+	user.ProtoReflect().SetUnknown(protopack.Message{protopack.Tag{Number: 300, Type: protopack.BytesType}, protopack.String("secret")}.Marshal())
+
+	call := func(t *testing.T, procedure string) (proto.Message, *unknownconnect.Report) {
+		var gotMsg proto.Message
+		var gotReport *unknownconnect.Report
+		interceptor := unknownconnect.NewInterceptor(
+			unknownconnect.WithCallback(func(ctx context.Context, s connect.Spec, m proto.Message) error {
+				gotMsg = m
+				return nil
+			}),
+			unknownconnect.WithReportCallback(func(ctx context.Context, s connect.Spec, r *unknownconnect.Report) error {
+				gotReport = r
+				return nil
+			}),
+			unknownconnect.WithRedaction(unknownconnect.Redaction{RawProcedures: []string{"/raw"}}),
+		)
+		unary := interceptor.WrapUnary(connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			assert.True(t, unknownconnect.MessageHasUnknownFields(req.Any().(proto.Message).ProtoReflect()), "the handler still gets the unknown fields")
+			return nil, nil
+		}))
+		_, err := unary(context.Background(), newRequestWithProcedure(user, procedure))
+		require.NoError(t, err)
+		return gotMsg, gotReport
+	}
+
+	t.Run("redacted", func(t *testing.T) {
+		msg, report := call(t, "/redacted")
+		assert.False(t, unknownconnect.MessageHasUnknownFields(msg.ProtoReflect()))
+		assert.Equal(t, "bob", msg.(*new.User).Name)
+		require.Len(t, report.Fields, 1)
+		assert.Nil(t, report.Fields[0].Raw)
+		assert.NotNil(t, report.Fields[0].HMAC)
+	})
+	t.Run("raw procedure", func(t *testing.T) {
+		msg, report := call(t, "/raw")
+		assert.Same(t, user, msg)
+		require.Len(t, report.Fields, 1)
+		assert.NotNil(t, report.Fields[0].Raw)
+		assert.Nil(t, report.Fields[0].HMAC)
+	})
+}
+
+// procedureRequest is a request for a specific procedure, as connect.NewRequest leaves it empty.
+type procedureRequest struct {
+	*connect.Request[new.User]
+	procedure string
+}
+
+func (r procedureRequest) Spec() connect.Spec {
+	return connect.Spec{Procedure: r.procedure}
+}
+
+func newRequestWithProcedure(user *new.User, procedure string) connect.AnyRequest {
+	return procedureRequest{Request: connect.NewRequest(user), procedure: procedure}
+}
@@ -0,0 +1,140 @@
+package unknownconnect
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// UnknownField is a single unknown field found while scanning a message.
+type UnknownField struct {
+	// Path is the location of the message holding the field, relative to the scanned message,
+	// like "user" or "msg_list[1].user". It is empty for fields of the scanned message itself.
+	Path string
+	// Parent is the message holding the unknown field.
+	Parent protoreflect.Message
+	// Name is the key of an unknown field found in JSON, see UnmarshalJSON. It is empty for fields
+	// found in the wire format.
+	Name string
+	// Number is the field number. It is zero for JSON keys and if the unknown bytes could not be
+	// parsed, in which case Raw holds the remaining unparsable bytes.
+	Number protowire.Number
+	// Type is the wire type of the field.
+	Type protowire.Type
+	// Kind tells whether the field is new to the local schema, or was removed from it.
+	Kind UnknownKind
+	// Raw is the field in the wire format, including its tag, or the value of a JSON key. It must
+	// not be modified. It is nil if the field was redacted.
+	Raw []byte
+	// HMAC and Length replace Raw when the field was redacted, see Report.Redact.
+	HMAC   []byte
+	Length int
+}
+
+// Report lists every unknown field found in a message.
+type Report struct {
+	Fields []UnknownField
+	// Truncated is set if the scan stopped early because it hit one of its ScanLimits, so there
+	// may be more unknown fields than listed.
+	Truncated bool
+	// Deprecated lists the deprecated fields and enum values set in the message. It is only filled
+	// in by interceptors created with WithDeprecated.
+	Deprecated []DeprecatedUsage
+}
+
+// NewReport scans the given message for unknown fields and returns a report describing them.
+func NewReport(msg protoreflect.Message) *Report {
+	r, _ := NewLimitedReport(msg, ScanLimits{})
+	return r
+}
+
+// Empty returns true if the report doesn't contain any unknown fields.
+func (r *Report) Empty() bool {
+	return len(r.Fields) == 0
+}
+
+// Size returns the total size in bytes of the unknown fields in the report.
+func (r *Report) Size() int {
+	var n int
+	for _, f := range r.Fields {
+		if f.HMAC != nil {
+			n += f.Length
+		} else {
+			n += len(f.Raw)
+		}
+	}
+	return n
+}
+
+func appendUnknownFields(fields []UnknownField, p string, msg protoreflect.Message) []UnknownField {
+	b := msg.GetUnknown()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeField(b)
+		if n < 0 {
+			return append(fields, UnknownField{Path: p, Parent: msg, Raw: b})
+		}
+		kind := kindOf(msg.Descriptor(), "", num, typ)
+		fields = append(fields, UnknownField{Path: p, Parent: msg, Number: num, Type: typ, Kind: kind, Raw: b[:n]})
+		b = b[n:]
+	}
+	return fields
+}
+
+// appendSetEnumDrift appends the values of the closed enum fields of msg that their enum doesn't
+// define. The Go runtime keeps them as set values rather than in the unknown fields, so Raw holds
+// each of them encoded on its own: the value of a singular field, an element of a list, or an
+// entry of a map.
+func appendSetEnumDrift(fields []UnknownField, p string, msg protoreflect.Message) []UnknownField {
+	drift := func(fd protoreflect.FieldDescriptor, typ protowire.Type, value []byte) {
+		raw := append(protowire.AppendTag(nil, fd.Number(), typ), value...)
+		fields = append(fields, UnknownField{Path: p, Parent: msg, Number: fd.Number(), Type: typ, Kind: KindEnumDrift, Raw: raw})
+	}
+	for _, fd := range walkPlanFor(msg.Descriptor()).closedEnums {
+		if !msg.Has(fd) {
+			continue
+		}
+		switch v := msg.Get(fd); {
+		case fd.IsMap():
+			ed := fd.MapValue().Enum()
+			v.Map().Range(func(mk protoreflect.MapKey, mv protoreflect.Value) bool {
+				if ed.Values().ByNumber(mv.Enum()) == nil {
+					drift(fd, protowire.BytesType, protowire.AppendBytes(nil, mapEntry(fd, mk, mv)))
+				}
+				return true
+			})
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				if n := list.Get(i).Enum(); fd.Enum().Values().ByNumber(n) == nil {
+					drift(fd, protowire.VarintType, protowire.AppendVarint(nil, uint64(n)))
+				}
+			}
+		default:
+			if n := v.Enum(); fd.Enum().Values().ByNumber(n) == nil {
+				drift(fd, protowire.VarintType, protowire.AppendVarint(nil, uint64(n)))
+			}
+		}
+	}
+	return fields
+}
+
+// mapEntry encodes the entry of the map field fd with the given key and value.
+func mapEntry(fd protoreflect.FieldDescriptor, mk protoreflect.MapKey, mv protoreflect.Value) []byte {
+	entry := dynamicpb.NewMessage(fd.Message())
+	entry.Set(fd.MapKey(), mk.Value())
+	entry.Set(fd.MapValue(), mv)
+	b, _ := proto.MarshalOptions{Deterministic: true}.Marshal(entry)
+	return b
+}
+
+// UnknownFieldsError is returned when a message is refused because it has unknown fields.
+type UnknownFieldsError struct {
+	Report *Report
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("message has %d unknown field(s)", len(e.Report.Fields))
+}
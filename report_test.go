@@ -0,0 +1,59 @@
+package unknownconnect_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protopack"
+)
+
+func TestNewReport(t *testing.T) {
+	t.Run("without unknown field", func(t *testing.T) {
+		user := &new.User{Name: "bob", Email: "bob@example.com"}
+		report := unknownconnect.NewReport(user.ProtoReflect())
+		assert.True(t, report.Empty())
+		assert.Equal(t, 0, report.Size())
+	})
+	t.Run("with nested unknown fields", func(t *testing.T) {
+		user := &new.User{Name: "bob"}
+		unknown := protopack.Message{
+			protopack.Tag{Number: 300, Type: protopack.Fixed32Type}, protopack.Int32(42),
+			protopack.Tag{Number: 301, Type: protopack.BytesType}, protopack.String("hi"),
+		}.Marshal()
+		user.ProtoReflect().SetUnknown(unknown)
+		req := &new.NewUserRequest{
+			User:    user,
+			MsgList: []*new.User{{}, user},
+			MsgMap:  map[int32]*new.User{7: user},
+		}
+
+		report := unknownconnect.NewReport(req.ProtoReflect())
+		require.Len(t, report.Fields, 6)
+		assert.Equal(t, len(unknown)*3, report.Size())
+
+		var paths []string
+		for _, f := range report.Fields {
+			paths = append(paths, f.Path)
+		}
+		assert.ElementsMatch(t, []string{"user", "user", "msg_map[7]", "msg_map[7]", "msg_list[1]", "msg_list[1]"}, paths)
+
+		first := report.Fields[0]
+		assert.Equal(t, protowire.Number(300), first.Number)
+		assert.Equal(t, protowire.Fixed32Type, first.Type)
+		assert.Equal(t, user.ProtoReflect(), first.Parent)
+		assert.Equal(t, unknown[:len(first.Raw)], first.Raw)
+	})
+	t.Run("with malformed unknown fields", func(t *testing.T) {
+		user := &new.User{}
+		user.ProtoReflect().SetUnknown(protoreflect.RawFields([]byte{8}))
+		report := unknownconnect.NewReport(user.ProtoReflect())
+		require.Len(t, report.Fields, 1)
+		assert.Equal(t, protowire.Number(0), report.Fields[0].Number)
+		assert.Equal(t, []byte{8}, report.Fields[0].Raw)
+	})
+}
@@ -0,0 +1,115 @@
+package unknownconnect
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// MetricSamplingRate is the probability that a message was inspected, recorded for every message
+// that is inspected when WithSampling is used. Dividing counts by it estimates the totals.
+const MetricSamplingRate = "sampling_rate"
+
+// samplingWindow is how often adaptive sampling recomputes the rate of a procedure.
+const samplingWindow = time.Second
+
+// Sampling configures which messages are inspected, for services where inspecting every message
+// costs too much.
+type Sampling struct {
+	// Rate is the fraction of messages that are inspected, from 0 to 1.
+	Rate float64
+	// ProcedureRates overrides Rate for specific procedures, like "/greet.v1.GreetService/Greet".
+	ProcedureRates map[string]float64
+	// Warmup is the number of messages per procedure that are always inspected after startup.
+	Warmup int
+	// CPUBudget makes the rate adaptive. The rate of each procedure is adjusted so that inspecting
+	// its messages takes about this much time per second, but never goes above the configured rate
+	// or below MinRate. Rate defaults to 1 when CPUBudget is set.
+	CPUBudget time.Duration
+	// MinRate is the lowest rate adaptive sampling goes to.
+	MinRate float64
+}
+
+func (s Sampling) rateFor(procedure string) float64 {
+	if rate, ok := s.ProcedureRates[procedure]; ok {
+		return rate
+	}
+	return s.Rate
+}
+
+type sampler struct {
+	config     Sampling
+	procedures sync.Map // procedure name to *procedureSampler
+	now        func() time.Time
+	random     func() float64
+}
+
+func newSampler(config Sampling) *sampler {
+	if config.CPUBudget > 0 && config.Rate == 0 {
+		// the adaptive rate is capped by Rate, and would never go above zero
+		config.Rate = 1
+	}
+	return &sampler{config: config, now: time.Now, random: rand.Float64}
+}
+
+// procedureSampler is the sampling state of a single procedure.
+type procedureSampler struct {
+	mu   sync.Mutex
+	seen int
+	rate float64
+
+	windowStart     time.Time
+	windowMessages  int
+	windowInspected int
+	windowCost      time.Duration
+}
+
+func (s *sampler) procedure(procedure string) *procedureSampler {
+	if ps, ok := s.procedures.Load(procedure); ok {
+		return ps.(*procedureSampler)
+	}
+	ps, _ := s.procedures.LoadOrStore(procedure, &procedureSampler{
+		rate:        s.config.rateFor(procedure),
+		windowStart: s.now(),
+	})
+	return ps.(*procedureSampler)
+}
+
+// sample decides whether the next message of the procedure is inspected and returns the rate the
+// decision was made with.
+func (s *sampler) sample(ps *procedureSampler) (bool, float64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.seen++
+	ps.windowMessages++
+	if ps.seen <= s.config.Warmup {
+		return true, 1
+	}
+	return s.random() < ps.rate, ps.rate
+}
+
+// done records how long inspecting a sampled message took and adapts the rate when a CPU budget is
+// configured.
+func (s *sampler) done(ps *procedureSampler, procedure string, cost time.Duration) {
+	if s.config.CPUBudget <= 0 {
+		return
+	}
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.windowInspected++
+	ps.windowCost += cost
+
+	now := s.now()
+	elapsed := now.Sub(ps.windowStart)
+	if elapsed < samplingWindow {
+		return
+	}
+	avgCost := ps.windowCost.Seconds() / float64(ps.windowInspected)
+	messagesPerSecond := float64(ps.windowMessages) / elapsed.Seconds()
+	if avgCost > 0 && messagesPerSecond > 0 {
+		rate := s.config.CPUBudget.Seconds() / (avgCost * messagesPerSecond)
+		ps.rate = min(max(rate, s.config.MinRate), s.config.rateFor(procedure))
+	}
+	ps.windowStart = now
+	ps.windowMessages, ps.windowInspected, ps.windowCost = 0, 0, 0
+}
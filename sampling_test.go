@@ -0,0 +1,122 @@
+package unknownconnect
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestSampler(t *testing.T) {
+	t.Run("warmup and fixed rate", func(t *testing.T) {
+		s := newSampler(Sampling{
+			Rate:           0.5,
+			ProcedureRates: map[string]float64{"/svc/Hot": 0.1},
+			Warmup:         2,
+		})
+		s.random = func() float64 { return 0.3 }
+
+		ps := s.procedure("/svc/Cold")
+		for i := 0; i < 2; i++ {
+			sampled, rate := s.sample(ps)
+			assert.True(t, sampled)
+			assert.Equal(t, 1.0, rate)
+		}
+		sampled, rate := s.sample(ps)
+		assert.True(t, sampled)
+		assert.Equal(t, 0.5, rate)
+
+		hot := s.procedure("/svc/Hot")
+		s.sample(hot)
+		s.sample(hot)
+		sampled, rate = s.sample(hot)
+		assert.False(t, sampled)
+		assert.Equal(t, 0.1, rate)
+		assert.Same(t, hot, s.procedure("/svc/Hot"))
+	})
+	t.Run("adaptive", func(t *testing.T) {
+		now := time.Unix(0, 0)
+		s := newSampler(Sampling{Rate: 1, MinRate: 0.01, CPUBudget: 10 * time.Millisecond})
+		s.now = func() time.Time { return now }
+		ps := s.procedure("/svc/Method")
+
+		// 100 messages a second, each taking 1ms to inspect, is 10 times the budget
+		for i := 0; i < 100; i++ {
+			sampled, _ := s.sample(ps)
+			require.True(t, sampled)
+			now = now.Add(10 * time.Millisecond)
+			s.done(ps, "/svc/Method", time.Millisecond)
+		}
+		_, rate := s.sample(ps)
+		assert.InDelta(t, 0.1, rate, 0.01)
+
+		// once inspecting gets cheap again, the rate goes back up to the configured rate
+		for i := 0; i < 10; i++ {
+			now = now.Add(100 * time.Millisecond)
+			s.done(ps, "/svc/Method", time.Microsecond)
+		}
+		_, rate = s.sample(ps)
+		assert.Equal(t, 1.0, rate)
+	})
+	t.Run("adaptive without rate", func(t *testing.T) {
+		s := newSampler(Sampling{CPUBudget: 10 * time.Millisecond})
+		sampled, rate := s.sample(s.procedure("/svc/Method"))
+		assert.True(t, sampled)
+		assert.Equal(t, 1.0, rate)
+	})
+}
+
+func TestInterceptorSampling(t *testing.T) {
+	msg := &new.User{Name: "bob"}
+	msg.ProtoReflect().SetUnknown(protoreflect.RawFields{8, 96, 01})
+
+	var called int
+	var rates []float64
+	interceptor := NewInterceptor(
+		WithSampling(Sampling{Rate: 0, Warmup: 2}),
+		WithCallback(func(context.Context, connect.Spec, proto.Message) error {
+			called++
+			return nil
+		}),
+		WithMetrics(MetricsFunc(func(_ context.Context, m Measurement) {
+			if m.Name == MetricSamplingRate {
+				rates = append(rates, m.Value)
+			}
+		})),
+	)
+	unary := interceptor.WrapUnary(func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+		return nil, nil
+	})
+	for i := 0; i < 5; i++ {
+		_, err := unary(context.Background(), connect.NewRequest(msg))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, called)
+	assert.Equal(t, []float64{1, 1}, rates)
+}
+
+func TestInterceptorSamplingUnknownLimits(t *testing.T) {
+	msg := &new.User{Name: "bob"}
+	msg.ProtoReflect().SetUnknown(protoreflect.RawFields{8, 96, 01})
+
+	var measurements []string
+	interceptor := NewInterceptor(
+		WithSampling(Sampling{Rate: 0}),
+		WithUnknownLimits(UnknownLimits{MaxBytesPerMessage: 1}),
+		WithMetrics(MetricsFunc(func(_ context.Context, m Measurement) {
+			measurements = append(measurements, m.Name)
+		})),
+	)
+	unary := interceptor.WrapUnary(func(context.Context, connect.AnyRequest) (connect.AnyResponse, error) {
+		return nil, nil
+	})
+	_, err := unary(context.Background(), connect.NewRequest(msg))
+	assert.ErrorIs(t, err, ErrUnknownLimitExceeded)
+	assert.Empty(t, measurements)
+}
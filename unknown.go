@@ -1,6 +1,13 @@
 package unknownconnect
 
-import "google.golang.org/protobuf/reflect/protoreflect"
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
 
 // DropUnknownFields recursively drops any unknown fields from the provided protobuf message.
 func DropUnknownFields(msg protoreflect.Message) {
@@ -23,48 +30,197 @@ func MessageHasUnknownFields(msg protoreflect.Message) bool {
 // ForEachUnknownField recursively scans the given protoreflect.Message object for unknown fields and calls the given callback
 // function when it finds a message containing an unknown field.
 func ForEachUnknownField(msg protoreflect.Message, cb func(msg protoreflect.Message) bool) {
-	forEachUnknownField(msg, cb)
+	w := &walker{cb: func(_ path, msg protoreflect.Message) bool {
+		return cb(msg)
+	}}
+	w.forEachUnknownField(msg, nil)
+}
+
+// path is the list of fields, list indexes and map keys that lead from the message being scanned
+// to a nested message. Siblings share the same backing array, so a path is only valid for the
+// duration of the callback it is passed to and should be formatted there.
+type path []pathElement
+
+type pathElement struct {
+	fd  protoreflect.FieldDescriptor
+	key string
+}
+
+func (p path) push(fd protoreflect.FieldDescriptor, key string) path {
+	return append(p, pathElement{fd: fd, key: key})
+}
+
+func (p path) String() string {
+	var s string
+	for _, el := range p {
+		name := string(el.fd.Name())
+		if el.fd.IsExtension() {
+			name = "[" + string(el.fd.FullName()) + "]"
+		}
+		s = wire.JoinPath(s, name, el.key)
+	}
+	return s
+}
+
+type walkFunc func(p path, msg protoreflect.Message) bool
+
+// walker holds the state of a single scan for unknown fields.
+type walker struct {
+	cb     walkFunc
+	limits ScanLimits
+	// enumDrift also passes messages with closed enum fields set to values their enum doesn't
+	// define to cb, see walkReportedFields.
+	enumDrift bool
+
+	nodes        int
+	unknownBytes int
+	// exceeded is set once one of the limits was hit, which stops the scan.
+	exceeded bool
 }
 
-func forEachUnknownField(msg protoreflect.Message, cb func(msg protoreflect.Message) bool) bool {
-	if len(msg.GetUnknown()) > 0 {
-		if !cb(msg) {
+// walkUnknownFields scans msg within the given limits and returns true if a limit was hit.
+func walkUnknownFields(msg protoreflect.Message, limits ScanLimits, cb walkFunc) bool {
+	w := &walker{cb: cb, limits: limits}
+	w.forEachUnknownField(msg, nil)
+	return w.exceeded
+}
+
+// walkReportedFields is like walkUnknownFields, but also calls cb for messages that have closed enum
+// fields set to values their enum doesn't define, which reports list as KindEnumDrift.
+func walkReportedFields(msg protoreflect.Message, limits ScanLimits, cb walkFunc) bool {
+	w := &walker{cb: cb, limits: limits, enumDrift: true}
+	w.forEachUnknownField(msg, nil)
+	return w.exceeded
+}
+
+func (w *walker) forEachUnknownField(msg protoreflect.Message, p path) bool {
+	if w.exceeded {
+		return false
+	}
+	w.nodes++
+	// every nested message adds exactly one element to the path
+	if (w.limits.MaxDepth > 0 && len(p) > w.limits.MaxDepth) || (w.limits.MaxNodes > 0 && w.nodes > w.limits.MaxNodes) {
+		w.exceeded = true
+		return false
+	}
+	plan := walkPlanFor(msg.Descriptor())
+	unknown := msg.GetUnknown()
+	if len(unknown) > 0 {
+		w.unknownBytes += len(unknown)
+		if w.limits.MaxUnknownBytes > 0 && w.unknownBytes > w.limits.MaxUnknownBytes {
+			w.exceeded = true
 			return false
 		}
 	}
+	drift := w.enumDrift && len(plan.closedEnums) > 0 && appendSetEnumDrift(nil, "", msg) != nil
+	if (len(unknown) > 0 || drift) && !w.cb(p, msg) {
+		return false
+	}
 
+	for _, fd := range plan.fields {
+		if msg.Has(fd) && !w.forEachFieldUnknownField(fd, msg.Get(fd), p) {
+			return false
+		}
+	}
+	if !plan.extensions {
+		return true
+	}
 	doContinue := true
 	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
-		doContinue = forEachFieldUnknownField(fd, v, cb)
+		if fd.IsExtension() {
+			doContinue = w.forEachFieldUnknownField(fd, v, p)
+		}
 		return doContinue
 	})
 	return doContinue
 }
 
-func forEachFieldUnknownField(fd protoreflect.FieldDescriptor, v protoreflect.Value, cb func(msg protoreflect.Message) bool) bool {
-	if fd.IsMap() {
+// walkPlan lists the fields of a message type that can hold other messages, directly, in a list
+// or as map values. Those are the only fields the walker has to look at, besides extensions. It
+// also lists the fields that hold values of closed enums, directly, in a list or as map values.
+type walkPlan struct {
+	fields      []protoreflect.FieldDescriptor
+	closedEnums []protoreflect.FieldDescriptor
+	extensions  bool
+}
+
+// walkPlans caches a *walkPlan per protoreflect.MessageDescriptor.
+var walkPlans planCache[walkPlan]
+
+func walkPlanFor(md protoreflect.MessageDescriptor) *walkPlan {
+	return walkPlans.get(md, func(md protoreflect.MessageDescriptor) *walkPlan {
+		plan := &walkPlan{extensions: md.ExtensionRanges().Len() > 0}
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			value := fd
+			if fd.IsMap() {
+				value = fd.MapValue()
+			}
+			switch {
+			case value.Message() != nil:
+				plan.fields = append(plan.fields, fd)
+			case value.Enum() != nil && wire.IsClosedEnum(value.Enum()):
+				plan.closedEnums = append(plan.closedEnums, fd)
+			}
+		}
+		return plan
+	})
+}
+
+// maxCachedPlans is the number of message types a planCache holds before starting over.
+const maxCachedPlans = 4096
+
+// planCache caches a plan per message descriptor. Descriptors are compared by identity, and dynamic
+// ones can be created without bound, by the command-line tool or when resolving types at runtime, so
+// the cache is emptied once it holds maxCachedPlans of them.
+type planCache[T any] struct {
+	plans atomic.Pointer[sync.Map]
+	size  atomic.Int64
+}
+
+func (c *planCache[T]) get(md protoreflect.MessageDescriptor, build func(protoreflect.MessageDescriptor) *T) *T {
+	plans := c.plans.Load()
+	if plans == nil {
+		c.plans.CompareAndSwap(nil, &sync.Map{})
+		plans = c.plans.Load()
+	}
+	if plan, ok := plans.Load(md); ok {
+		return plan.(*T)
+	}
+	actual, loaded := plans.LoadOrStore(md, build(md))
+	if !loaded && c.size.Add(1) > maxCachedPlans {
+		c.size.Store(0)
+		c.plans.Store(&sync.Map{})
+	}
+	return actual.(*T)
+}
+
+// forEachFieldUnknownField walks the messages held by a field, directly, in a list or as map
+// values, and returns false as soon as one of them stops the walk.
+func (w *walker) forEachFieldUnknownField(fd protoreflect.FieldDescriptor, v protoreflect.Value, p path) bool {
+	switch {
+	case fd.IsMap():
+		if fd.MapValue().Message() == nil {
+			return true
+		}
+		doContinue := true
 		v.Map().Range(func(mk protoreflect.MapKey, mv protoreflect.Value) bool {
-			return !forEachFieldUnknownField(fd.MapValue(), mv, cb)
+			doContinue = w.forEachUnknownField(mv.Message(), p.push(fd, wire.MapKey(mk)))
+			return doContinue
 		})
+		return doContinue
+	case fd.Message() == nil:
 		return true
-	}
-
-	switch fd.Kind() {
-	case protoreflect.MessageKind, protoreflect.GroupKind:
-		if fd.IsList() {
-			list := v.List()
-			for i := 0; i < list.Len(); i++ {
-				vv := list.Get(i)
-				if !forEachUnknownField(vv.Message(), cb) {
-					return false
-				}
-			}
-		} else {
-			if !forEachUnknownField(v.Message(), cb) {
+	case fd.IsList():
+		list := v.List()
+		for i := 0; i < list.Len(); i++ {
+			if !w.forEachUnknownField(list.Get(i).Message(), p.push(fd, fmt.Sprint(i))) {
 				return false
 			}
 		}
+		return true
 	default:
+		return w.forEachUnknownField(v.Message(), p.push(fd, ""))
 	}
-	return true
 }
@@ -0,0 +1,243 @@
+package unknownconnecttest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/sudorandom/unknownconnect-go"
+	"github.com/sudorandom/unknownconnect-go/internal/wire"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxRandomDepth bounds how deep RandomMessage nests messages, for recursive types.
+const maxRandomDepth = 3
+
+// RandomMessage returns a message of the given type with random values in a random selection of
+// its fields, including lists, maps, oneofs, enums and nested messages. Required fields are always
+// set.
+func RandomMessage(md protoreflect.MessageDescriptor, r *rand.Rand) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(md)
+	fillMessage(msg, r, maxRandomDepth)
+	return msg
+}
+
+func fillMessage(msg protoreflect.Message, r *rand.Rand, depth int) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		// required fields are always set, or the message couldn't be marshalled
+		required := fd.Cardinality() == protoreflect.Required
+		if fd.Message() != nil && depth <= 0 && !required {
+			continue
+		}
+		if oneof := fd.ContainingOneof(); oneof != nil && msg.WhichOneof(oneof) != nil {
+			continue
+		}
+		if !required && r.Intn(2) == 0 {
+			continue
+		}
+		switch {
+		case fd.IsMap():
+			m := msg.Mutable(fd).Map()
+			for n := r.Intn(4); n > 0; n-- {
+				m.Set(randomValue(fd.MapKey(), nil, r, depth).MapKey(), randomValue(fd.MapValue(), m.NewValue, r, depth))
+			}
+		case fd.IsList():
+			list := msg.Mutable(fd).List()
+			for n := r.Intn(4); n > 0; n-- {
+				list.Append(randomValue(fd, list.NewElement, r, depth))
+			}
+		case fd.Message() != nil:
+			fillMessage(msg.Mutable(fd).Message(), r, depth-1)
+		default:
+			msg.Set(fd, randomValue(fd, nil, r, depth))
+		}
+	}
+}
+
+// randomValue returns a random value for the field. newMessage creates the message for message
+// values in lists and maps.
+func randomValue(fd protoreflect.FieldDescriptor, newMessage func() protoreflect.Value, r *rand.Rand, depth int) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(r.Intn(2) == 0)
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		return protoreflect.ValueOfEnum(values.Get(r.Intn(values.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(r.Int31() - r.Int31())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(r.Uint32())
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(r.Int63() - r.Int63())
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(r.Uint64())
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(r.Float32())
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(r.NormFloat64())
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(randomString(r))
+	case protoreflect.BytesKind:
+		b := make([]byte, r.Intn(8))
+		r.Read(b)
+		return protoreflect.ValueOfBytes(b)
+	default:
+		v := newMessage()
+		fillMessage(v.Message(), r, depth-1)
+		return v
+	}
+}
+
+func randomString(r *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, r.Intn(8))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// CheckMixedVersions generates a random message of the newer type from seed, unmarshals it into the
+// older type and fails the test unless the report of unknown fields lists exactly the fields the
+// older type can't parse, and those set to values its closed enums don't define. Fields both versions have must not change between messages and other
+// types. It is meant to be called from a fuzz target:
+//
+//	func FuzzUserVersions(f *testing.F) {
+//		f.Add(int64(1))
+//		f.Fuzz(func(t *testing.T, seed int64) {
+//			unknownconnecttest.CheckMixedVersions(t, (&v2.User{}).ProtoReflect().Descriptor(), (&v1.User{}).ProtoReflect().Descriptor(), seed)
+//		})
+//	}
+func CheckMixedVersions(tb testing.TB, newer, older protoreflect.MessageDescriptor, seed int64) {
+	tb.Helper()
+	msg := RandomMessage(newer, rand.New(rand.NewSource(seed)))
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		tb.Fatalf("marshalling %s: %v", newer.FullName(), err)
+	}
+	olderMsg := dynamicpb.NewMessage(older)
+	if err := proto.Unmarshal(b, olderMsg); err != nil {
+		tb.Fatalf("unmarshalling into %s: %v", older.FullName(), err)
+	}
+
+	want := map[string]bool{}
+	if err := expectUnknown(want, "", msg, older); err != nil {
+		tb.Fatal(err)
+	}
+	got := map[string]bool{}
+	// set values of closed enums are reported, but aren't unknown fields of the message
+	var reportsUnknown bool
+	for _, f := range unknownconnect.NewReport(olderMsg).Fields {
+		got[fieldKey(f.Path, f.Number)] = true
+		reportsUnknown = reportsUnknown || f.Kind != unknownconnect.KindEnumDrift
+	}
+	if wantKeys, gotKeys := sortedKeys(want), sortedKeys(got); fmt.Sprint(wantKeys) != fmt.Sprint(gotKeys) {
+		tb.Errorf("seed %d: reported unknown fields %v, want %v\nmessage: %v", seed, gotKeys, wantKeys, msg)
+	}
+	if has := unknownconnect.MessageHasUnknownFields(olderMsg); has != reportsUnknown {
+		tb.Errorf("seed %d: MessageHasUnknownFields returned %t, want %t", seed, has, reportsUnknown)
+	}
+}
+
+// expectUnknown adds the fields of msg that a message of type older leaves unknown to want, and
+// the fields holding values its closed enums don't define, which reports list as drift. Values of
+// open enums the older type doesn't know aren't expected.
+func expectUnknown(want map[string]bool, p string, msg protoreflect.Message, older protoreflect.MessageDescriptor) error {
+	var err error
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		ofd := older.Fields().ByNumber(fd.Number())
+		if ofd == nil || !wireCompatible(fd, ofd) || enumDrift(fd, ofd, v) {
+			want[fieldKey(p, fd.Number())] = true
+			return true
+		}
+		if fd.Message() == nil || (fd.IsMap() && fd.MapValue().Message() == nil) {
+			return true
+		}
+		name := string(ofd.Name())
+		switch {
+		case fd.IsMap():
+			v.Map().Range(func(mk protoreflect.MapKey, mv protoreflect.Value) bool {
+				err = expectUnknown(want, wire.JoinPath(p, name, wire.MapKey(mk)), mv.Message(), ofd.MapValue().Message())
+				return err == nil
+			})
+		case fd.IsList():
+			for i := 0; i < v.List().Len() && err == nil; i++ {
+				err = expectUnknown(want, wire.JoinPath(p, name, fmt.Sprint(i)), v.List().Get(i).Message(), ofd.Message())
+			}
+		default:
+			err = expectUnknown(want, wire.JoinPath(p, name, ""), v.Message(), ofd.Message())
+		}
+		return err == nil
+	})
+	return err
+}
+
+// enumDrift returns true if v, the value of the newer enum field fd, holds values that the closed
+// enum of the older field ofd doesn't define.
+func enumDrift(fd, ofd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+	value, older := fd, ofd
+	if fd.IsMap() {
+		value, older = fd.MapValue(), ofd.MapValue()
+	}
+	if value.Enum() == nil || older.Enum() == nil || !wire.IsClosedEnum(older.Enum()) {
+		return false
+	}
+	defined := func(v protoreflect.Value) bool {
+		return older.Enum().Values().ByNumber(v.Enum()) != nil
+	}
+	switch {
+	case fd.IsMap():
+		drift := false
+		v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+			drift = !defined(mv)
+			return !drift
+		})
+		return drift
+	case fd.IsList():
+		for i := 0; i < v.List().Len(); i++ {
+			if !defined(v.List().Get(i)) {
+				return true
+			}
+		}
+		return false
+	default:
+		return !defined(v)
+	}
+}
+
+// wireCompatible returns true if the older field parses what the newer one wrote. Fields that changed
+// between a message and another type count as incompatible, although what happens to them really
+// depends on the data.
+func wireCompatible(fd, ofd protoreflect.FieldDescriptor) bool {
+	if fd.IsMap() != ofd.IsMap() || (fd.Message() == nil) != (ofd.Message() == nil) {
+		return false
+	}
+	if fd.IsMap() {
+		return wire.Accepts(ofd.MapKey(), wire.Type(fd.MapKey().Kind())) &&
+			wire.Accepts(ofd.MapValue(), wire.Type(fd.MapValue().Kind()))
+	}
+	typ := wire.Type(fd.Kind())
+	if fd.IsPacked() {
+		typ = protowire.BytesType
+	}
+	return wire.Accepts(ofd, typ)
+}
+
+func fieldKey(p string, num protowire.Number) string {
+	return fmt.Sprintf("%s#%d", p, num)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
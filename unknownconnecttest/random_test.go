@@ -0,0 +1,132 @@
+package unknownconnecttest_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"github.com/sudorandom/unknownconnect-go/internal/protobuild"
+	"github.com/sudorandom/unknownconnect-go/unknownconnecttest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const mixedPackage = "mixed"
+
+func file(t testing.TB, syntax string, enums []*descriptorpb.EnumDescriptorProto, messages ...*descriptorpb.DescriptorProto) protoreflect.FileDescriptor {
+	t.Helper()
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String(syntax + "/mixed.proto"),
+		Package:     proto.String(mixedPackage),
+		Syntax:      proto.String(syntax),
+		EnumType:    enums,
+		MessageType: messages,
+	}, nil)
+	require.NoError(t, err)
+	return fd
+}
+
+// mixedVersions returns pairs of newer and older versions of message types, covering maps, lists,
+// oneofs, enums and nested messages in proto3 and proto2.
+func mixedVersions(t testing.TB) [][2]protoreflect.MessageDescriptor {
+	newerEvent := func(syntax string) protoreflect.MessageDescriptor {
+		item := protobuild.Message("Item",
+			protobuild.Field("name", 1, protobuild.TypeString, ""),
+			protobuild.Field("count", 2, protobuild.TypeInt64, ""),
+			protobuild.Field("color", 3, protobuild.TypeEnum, ".mixed.Color"),
+			protobuild.Field("weight", 4, protobuild.TypeDouble, ""),
+			protobuild.Field("children", 5, protobuild.TypeMessage, ".mixed.Item").List(),
+		)
+		event := protobuild.Message("Event",
+			protobuild.Field("id", 1, protobuild.TypeString, ""),
+			protobuild.Field("item", 2, protobuild.TypeMessage, ".mixed.Item"),
+			protobuild.Field("items", 3, protobuild.TypeMessage, ".mixed.Item").List(),
+			protobuild.Field("text", 5, protobuild.TypeString, "").InOneof(0),
+			protobuild.Field("extra", 6, protobuild.TypeMessage, ".mixed.Item").InOneof(0),
+			protobuild.Field("codes", 7, protobuild.TypeInt32, "").List(),
+			protobuild.Field("checksum", 9, protobuild.TypeFixed32, ""),
+			protobuild.Field("blob", 10, protobuild.TypeBytes, ""),
+			protobuild.Field("color", 11, protobuild.TypeEnum, ".mixed.Color"),
+			protobuild.Field("delta", 12, protobuild.TypeSint64, ""),
+			protobuild.Field("colors", 13, protobuild.TypeEnum, ".mixed.Color").List(),
+		)
+		event.OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("payload")}}
+		event.Field = append(event.Field,
+			protobuild.MapOf(event, "by_name", 4, protobuild.Field("key", 1, protobuild.TypeString, ""), protobuild.Field("value", 2, protobuild.TypeMessage, ".mixed.Item")).Proto(),
+			protobuild.MapOf(event, "labels", 8, protobuild.Field("key", 1, protobuild.TypeInt32, ""), protobuild.Field("value", 2, protobuild.TypeString, "")).Proto(),
+		)
+		fd := file(t, syntax, []*descriptorpb.EnumDescriptorProto{protobuild.Enum("Color", "COLOR_UNSPECIFIED", "RED", "GREEN", "BLUE")}, item, event)
+		return fd.Messages().ByName("Event")
+	}
+	olderEvent := func(syntax string) protoreflect.MessageDescriptor {
+		item := protobuild.Message("Item",
+			protobuild.Field("name", 1, protobuild.TypeString, ""),
+			protobuild.Field("count", 2, protobuild.TypeInt64, ""),
+			protobuild.Field("color", 3, protobuild.TypeEnum, ".mixed.Color"),
+		)
+		event := protobuild.Message("Event",
+			protobuild.Field("id", 1, protobuild.TypeString, ""),
+			protobuild.Field("item", 2, protobuild.TypeMessage, ".mixed.Item"),
+			protobuild.Field("items", 3, protobuild.TypeMessage, ".mixed.Item").List(),
+			protobuild.Field("text", 5, protobuild.TypeString, "").InOneof(0),
+			protobuild.Field("blob", 10, protobuild.TypeBytes, ""),
+			protobuild.Field("color", 11, protobuild.TypeEnum, ".mixed.Color"),
+			protobuild.Field("colors", 13, protobuild.TypeEnum, ".mixed.Color").List(),
+		)
+		event.OneofDecl = []*descriptorpb.OneofDescriptorProto{{Name: proto.String("payload")}}
+		event.Field = append(event.Field,
+			protobuild.MapOf(event, "by_name", 4, protobuild.Field("key", 1, protobuild.TypeString, ""), protobuild.Field("value", 2, protobuild.TypeMessage, ".mixed.Item")).Proto(),
+		)
+		fd := file(t, syntax, []*descriptorpb.EnumDescriptorProto{protobuild.Enum("Color", "COLOR_UNSPECIFIED", "RED")}, item, event)
+		return fd.Messages().ByName("Event")
+	}
+	return [][2]protoreflect.MessageDescriptor{
+		{new.File_internal_proto_new_user_proto.Messages().ByName("NewUserRequest"), old.File_internal_proto_old_user_proto.Messages().ByName("NewUserRequest")},
+		{newerEvent("proto3"), olderEvent("proto3")},
+		// proto2 lists aren't packed and enums are closed
+		{newerEvent("proto2"), olderEvent("proto2")},
+	}
+}
+
+func FuzzMixedVersions(f *testing.F) {
+	for seed := int64(0); seed < 50; seed++ {
+		f.Add(seed)
+	}
+	versions := mixedVersions(f)
+	f.Fuzz(func(t *testing.T, seed int64) {
+		for _, v := range versions {
+			unknownconnecttest.CheckMixedVersions(t, v[0], v[1], seed)
+		}
+	})
+}
+
+func TestRandomMessage(t *testing.T) {
+	md := mixedVersions(t)[1][0]
+	// the same seed gives the same message
+	a := unknownconnecttest.RandomMessage(md, rand.New(rand.NewSource(7)))
+	b := unknownconnecttest.RandomMessage(md, rand.New(rand.NewSource(7)))
+	require.True(t, proto.Equal(a, b))
+}
+
+func TestRandomMessageRequired(t *testing.T) {
+	item := protobuild.Message("Item",
+		protobuild.Field("name", 1, protobuild.TypeString, "").Required(),
+		protobuild.Field("count", 2, protobuild.TypeInt64, ""),
+	)
+	order := protobuild.Message("Order",
+		protobuild.Field("id", 1, protobuild.TypeString, "").Required(),
+		protobuild.Field("item", 2, protobuild.TypeMessage, ".mixed.Item").Required(),
+		protobuild.Field("items", 3, protobuild.TypeMessage, ".mixed.Item").List(),
+		protobuild.Field("next", 4, protobuild.TypeMessage, ".mixed.Order"),
+	)
+	md := file(t, "proto2", nil, item, order).Messages().ByName("Order")
+	for seed := int64(0); seed < 50; seed++ {
+		msg := unknownconnecttest.RandomMessage(md, rand.New(rand.NewSource(seed)))
+		_, err := proto.Marshal(msg)
+		require.NoError(t, err, "seed %d", seed)
+	}
+}
@@ -0,0 +1,149 @@
+// Package unknownconnecttest provides utilities for testing that services and clients stay
+// compatible with peers using other versions of their schema.
+//
+// A typical test serves a handler built with older generated code and calls it with a client built
+// with newer generated code, or the other way around, through an in-memory transport:
+//
+//	checker := unknownconnecttest.NewChecker(t)
+//	_, handler := greetv1connect.NewGreetServiceHandler(&server{}, checker.Option())
+//	client := greetv2connect.NewGreetServiceClient(unknownconnecttest.NewClient(handler), unknownconnecttest.URL)
+//	_, err := client.Greet(ctx, connect.NewRequest(&greetv2.GreetRequest{Name: "bob"}))
+//
+// The test fails if the handler sees any unknown fields.
+package unknownconnecttest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/sudorandom/unknownconnect-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// URL is a base URL to use with clients created with NewClient. Any other URL works too.
+const URL = "http://unknownconnecttest"
+
+// Seen is a message with unknown fields seen by a Checker.
+type Seen struct {
+	Spec   connect.Spec
+	Report *unknownconnect.Report
+}
+
+// Checker records every message with unknown fields seen by its interceptor and, unless created
+// with NewRecorder, fails the test for each of them.
+type Checker struct {
+	tb          testing.TB
+	fail        bool
+	interceptor connect.Interceptor
+
+	mu   sync.Mutex
+	seen []Seen
+}
+
+// NewChecker creates a Checker that fails the test whenever a message with unknown fields is seen.
+// Failures are reported with tb.Errorf, so they can happen on any goroutine.
+func NewChecker(tb testing.TB) *Checker {
+	return newChecker(tb, true)
+}
+
+// NewRecorder creates a Checker that only records messages with unknown fields, for tests that
+// expect them and make assertions on Seen.
+func NewRecorder(tb testing.TB) *Checker {
+	return newChecker(tb, false)
+}
+
+func newChecker(tb testing.TB, fail bool) *Checker {
+	c := &Checker{tb: tb, fail: fail}
+	c.interceptor = unknownconnect.NewInterceptor(unknownconnect.WithReportCallback(c.record))
+	return c
+}
+
+func (c *Checker) record(_ context.Context, spec connect.Spec, report *unknownconnect.Report) error {
+	c.mu.Lock()
+	c.seen = append(c.seen, Seen{Spec: spec, Report: report})
+	c.mu.Unlock()
+	if c.fail {
+		c.tb.Errorf("%s received a message with unknown fields:\n%s", describe(spec), formatReport(report))
+	}
+	return nil
+}
+
+// Interceptor returns the interceptor that checks messages.
+func (c *Checker) Interceptor() connect.Interceptor {
+	return c.interceptor
+}
+
+// Option returns an option adding the interceptor to a handler or client.
+func (c *Checker) Option() connect.Option {
+	return connect.WithInterceptors(c.interceptor)
+}
+
+// Seen returns the messages with unknown fields seen so far.
+func (c *Checker) Seen() []Seen {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Seen(nil), c.seen...)
+}
+
+// Reset forgets the messages seen so far.
+func (c *Checker) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seen = nil
+}
+
+// AssertNoUnknownFields fails the test if msg has any unknown fields.
+func AssertNoUnknownFields(tb testing.TB, msg proto.Message) bool {
+	tb.Helper()
+	report := unknownconnect.NewReport(msg.ProtoReflect())
+	if report.Empty() {
+		return true
+	}
+	tb.Errorf("%s has unknown fields:\n%s", msg.ProtoReflect().Descriptor().FullName(), formatReport(report))
+	return false
+}
+
+// NewClient returns a connect.HTTPClient that sends requests straight to handler, without any
+// network. Responses are buffered, so bidirectional streams that need both sides to be open at the
+// same time aren't supported.
+func NewClient(handler http.Handler) connect.HTTPClient {
+	return &localClient{handler: handler}
+}
+
+type localClient struct {
+	handler http.Handler
+}
+
+func (c *localClient) Do(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	c.handler.ServeHTTP(recorder, req)
+	return recorder.Result(), nil
+}
+
+func describe(spec connect.Spec) string {
+	side := "handler"
+	if spec.IsClient {
+		side = "client"
+	}
+	if spec.Procedure == "" {
+		return side
+	}
+	return side + " of " + spec.Procedure
+}
+
+func formatReport(report *unknownconnect.Report) string {
+	var sb strings.Builder
+	for _, f := range report.Fields {
+		fmt.Fprintf(&sb, "    %s\n", f)
+	}
+	if report.Truncated {
+		sb.WriteString("    ...\n")
+	}
+	return sb.String()
+}
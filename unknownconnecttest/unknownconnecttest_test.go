@@ -0,0 +1,100 @@
+package unknownconnecttest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/new/newconnect"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old"
+	"github.com/sudorandom/unknownconnect-go/internal/proto/old/oldconnect"
+	"github.com/sudorandom/unknownconnect-go/unknownconnecttest"
+)
+
+// recordingTB records failures instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+type oldServer struct {
+	oldconnect.UnimplementedUserManagementHandler
+}
+
+func (oldServer) NewUser(_ context.Context, req *connect.Request[old.NewUserRequest]) (*connect.Response[old.NewUserResponse], error) {
+	return connect.NewResponse(&old.NewUserResponse{}), nil
+}
+
+type newServer struct {
+	newconnect.UnimplementedUserManagementHandler
+}
+
+func (newServer) NewUser(_ context.Context, req *connect.Request[new.NewUserRequest]) (*connect.Response[new.NewUserResponse], error) {
+	return connect.NewResponse(&new.NewUserResponse{User: req.Msg.User}), nil
+}
+
+func TestChecker(t *testing.T) {
+	t.Run("newer client", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		checker := unknownconnecttest.NewChecker(tb)
+		_, handler := oldconnect.NewUserManagementHandler(oldServer{}, checker.Option())
+		// the old handler under the procedure of the old schema, called with the new messages
+		client := connect.NewClient[new.NewUserRequest, new.NewUserResponse](
+			unknownconnecttest.NewClient(handler),
+			unknownconnecttest.URL+oldconnect.UserManagementNewUserProcedure,
+		)
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(&new.NewUserRequest{
+			User: &new.User{Name: "bob", Email: "bob@example.com"},
+		}))
+		require.NoError(t, err)
+
+		require.Len(t, tb.errors, 1)
+		assert.Contains(t, tb.errors[0], "handler of /helloworld.old.UserManagement/NewUser")
+		assert.Contains(t, tb.errors[0], `user: 2: "bob@example.com"`)
+		seen := checker.Seen()
+		require.Len(t, seen, 1)
+		assert.Len(t, seen[0].Report.Fields, 1)
+	})
+
+	t.Run("older client", func(t *testing.T) {
+		recorder := unknownconnecttest.NewRecorder(t)
+		_, handler := newconnect.NewUserManagementHandler(newServer{})
+		client := connect.NewClient[old.NewUserRequest, old.NewUserResponse](
+			unknownconnecttest.NewClient(handler),
+			unknownconnecttest.URL+newconnect.UserManagementNewUserProcedure,
+			recorder.Option(),
+		)
+		_, err := client.CallUnary(context.Background(), connect.NewRequest(&old.NewUserRequest{User: &old.User{Name: "bob"}}))
+		require.NoError(t, err)
+
+		// the old response doesn't have the user field
+		seen := recorder.Seen()
+		require.Len(t, seen, 1)
+		assert.True(t, seen[0].Spec.IsClient)
+		require.Len(t, seen[0].Report.Fields, 1)
+		assert.EqualValues(t, 1, seen[0].Report.Fields[0].Number)
+
+		recorder.Reset()
+		assert.Empty(t, recorder.Seen())
+	})
+}
+
+func TestAssertNoUnknownFields(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	assert.True(t, unknownconnecttest.AssertNoUnknownFields(tb, &old.User{Name: "bob"}))
+	user := &old.User{Name: "bob"}
+	user.ProtoReflect().SetUnknown([]byte{0x10, 0x01})
+	assert.False(t, unknownconnecttest.AssertNoUnknownFields(tb, user))
+	require.Len(t, tb.errors, 1)
+	assert.Contains(t, tb.errors[0], "helloworld.old.User has unknown fields")
+}
@@ -0,0 +1,273 @@
+package unknownconnect
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/sudorandom/unknownconnect-go/internal/protobuild"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// rangeWalk is the walk from before walk plans, which looks at every populated field of every
+// message. It is kept to compare against in benchmarks.
+func rangeWalk(msg protoreflect.Message, cb func(msg protoreflect.Message) bool) bool {
+	if len(msg.GetUnknown()) > 0 {
+		if !cb(msg) {
+			return false
+		}
+	}
+	doContinue := true
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		doContinue = rangeWalkField(fd, v, cb)
+		return doContinue
+	})
+	return doContinue
+}
+
+func rangeWalkField(fd protoreflect.FieldDescriptor, v protoreflect.Value, cb func(msg protoreflect.Message) bool) bool {
+	if fd.IsMap() {
+		doContinue := true
+		v.Map().Range(func(mk protoreflect.MapKey, mv protoreflect.Value) bool {
+			doContinue = rangeWalkField(fd.MapValue(), mv, cb)
+			return doContinue
+		})
+		return doContinue
+	}
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if fd.IsList() {
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				if !rangeWalk(list.Get(i).Message(), cb) {
+					return false
+				}
+			}
+		} else if !rangeWalk(v.Message(), cb) {
+			return false
+		}
+	default:
+	}
+	return true
+}
+
+// benchNodeDescriptor describes a message with many scalar fields, a nested message and a list and
+// map of nested messages, which is enough to build both deep and wide trees.
+func benchNodeDescriptor(tb testing.TB) protoreflect.MessageDescriptor {
+	tb.Helper()
+	node := protobuild.Message("Node",
+		protobuild.Field("child", 1, protobuild.TypeMessage, ".bench.Node"),
+		protobuild.Field("children", 2, protobuild.TypeMessage, ".bench.Node").List(),
+	)
+	node.Field = append(node.Field, protobuild.MapOf(node, "by_name", 3, protobuild.Field("key", 1, protobuild.TypeString, ""), protobuild.Field("value", 2, protobuild.TypeMessage, ".bench.Node")).Proto())
+	for i := int32(10); i < 30; i++ {
+		node.Field = append(node.Field, protobuild.Field(fmt.Sprint("scalar", i), i, protobuild.TypeInt64, "").Proto())
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("bench.proto"),
+		Package:     proto.String("bench"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{node},
+	}, nil)
+	require.NoError(tb, err)
+	return fd.Messages().Get(0)
+}
+
+func newBenchNode(md protoreflect.MessageDescriptor) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(md)
+	for i := 10; i < 30; i++ {
+		msg.Set(md.Fields().ByNumber(protoreflect.FieldNumber(i)), protoreflect.ValueOfInt64(int64(i)))
+	}
+	return msg
+}
+
+// deepBenchNode builds a chain of depth nodes.
+func deepBenchNode(md protoreflect.MessageDescriptor, depth int) *dynamicpb.Message {
+	root := newBenchNode(md)
+	msg := root
+	for i := 1; i < depth; i++ {
+		child := newBenchNode(md)
+		msg.Set(md.Fields().ByName("child"), protoreflect.ValueOfMessage(child))
+		msg = child
+	}
+	return root
+}
+
+// wideBenchNode builds a node with width children in a list and width children in a map.
+func wideBenchNode(md protoreflect.MessageDescriptor, width int) *dynamicpb.Message {
+	root := newBenchNode(md)
+	list := root.Mutable(md.Fields().ByName("children")).List()
+	byName := root.Mutable(md.Fields().ByName("by_name")).Map()
+	for i := 0; i < width; i++ {
+		list.Append(protoreflect.ValueOfMessage(newBenchNode(md)))
+		byName.Set(protoreflect.ValueOfString(fmt.Sprint(i)).MapKey(), protoreflect.ValueOfMessage(newBenchNode(md)))
+	}
+	return root
+}
+
+func TestWalkPlan(t *testing.T) {
+	md := benchNodeDescriptor(t)
+	plan := walkPlanFor(md)
+	require.Len(t, plan.fields, 3)
+	require.False(t, plan.extensions)
+	require.Same(t, plan, walkPlanFor(md))
+
+	t.Run("concurrent", func(t *testing.T) {
+		md := benchNodeDescriptor(t)
+		plans := make([]*walkPlan, 8)
+		var wg sync.WaitGroup
+		for i := range plans {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				plans[i] = walkPlanFor(md)
+			}(i)
+		}
+		wg.Wait()
+		for _, plan := range plans {
+			require.Same(t, plans[0], plan)
+		}
+	})
+	t.Run("bounded", func(t *testing.T) {
+		// distinct descriptors, like those of dynamic messages built over and over
+		type dynamicDescriptor struct {
+			protoreflect.MessageDescriptor
+			id int
+		}
+		var cache planCache[int]
+		builds := 0
+		build := func(protoreflect.MessageDescriptor) *int {
+			builds++
+			return &builds
+		}
+		for i := 0; i < maxCachedPlans; i++ {
+			cache.get(dynamicDescriptor{md, i}, build)
+		}
+		cache.get(dynamicDescriptor{md, 0}, build)
+		require.Equal(t, maxCachedPlans, builds)
+		cache.get(dynamicDescriptor{md, maxCachedPlans}, build)
+		cache.get(dynamicDescriptor{md, 0}, build)
+		require.Equal(t, maxCachedPlans+2, builds)
+	})
+	t.Run("finds unknown fields", func(t *testing.T) {
+		root := deepBenchNode(md, 10)
+		leaf := root
+		for leaf.Has(md.Fields().ByName("child")) {
+			leaf = leaf.Get(md.Fields().ByName("child")).Message().(*dynamicpb.Message)
+		}
+		require.False(t, MessageHasUnknownFields(root))
+		leaf.SetUnknown(protoreflect.RawFields{8, 1})
+		require.True(t, MessageHasUnknownFields(root))
+		require.Equal(t, "child.child.child.child.child.child.child.child.child", NewReport(root).Fields[0].Path)
+	})
+}
+
+func TestWalkStop(t *testing.T) {
+	md := benchNodeDescriptor(t)
+	plain := func() *dynamicpb.Message { return dynamicpb.NewMessage(md) }
+	leaf := func() *dynamicpb.Message {
+		msg := plain()
+		msg.SetUnknown(protoreflect.RawFields{8, 1})
+		return msg
+	}
+	withChild := func(msg, child *dynamicpb.Message) *dynamicpb.Message {
+		msg.Set(md.Fields().ByName("child"), protoreflect.ValueOfMessage(child))
+		return msg
+	}
+	withChildren := func(msg *dynamicpb.Message, children ...*dynamicpb.Message) *dynamicpb.Message {
+		list := msg.Mutable(md.Fields().ByName("children")).List()
+		for _, child := range children {
+			list.Append(protoreflect.ValueOfMessage(child))
+		}
+		return msg
+	}
+	withByName := func(msg *dynamicpb.Message, children ...*dynamicpb.Message) *dynamicpb.Message {
+		byName := msg.Mutable(md.Fields().ByName("by_name")).Map()
+		for i, child := range children {
+			byName.Set(protoreflect.ValueOfString(fmt.Sprint(i)).MapKey(), protoreflect.ValueOfMessage(child))
+		}
+		return msg
+	}
+
+	// every message has exactly three messages with unknown fields
+	for _, tc := range []struct {
+		name string
+		msg  func() *dynamicpb.Message
+	}{
+		{name: "singular", msg: func() *dynamicpb.Message { return withChild(leaf(), withChild(leaf(), leaf())) }},
+		{name: "list", msg: func() *dynamicpb.Message { return withChildren(plain(), leaf(), plain(), leaf(), leaf()) }},
+		{name: "map", msg: func() *dynamicpb.Message { return withByName(plain(), leaf(), plain(), leaf(), leaf()) }},
+		{name: "map in list", msg: func() *dynamicpb.Message {
+			return withChildren(plain(), plain(), withByName(plain(), leaf(), leaf()), withByName(plain(), plain(), leaf()))
+		}},
+		{name: "list in map", msg: func() *dynamicpb.Message {
+			return withByName(plain(), withChildren(plain(), leaf(), leaf()), withChildren(plain(), plain(), leaf()))
+		}},
+		{name: "map in map", msg: func() *dynamicpb.Message {
+			return withByName(plain(), withByName(plain(), leaf(), plain()), withByName(plain(), leaf(), leaf()))
+		}},
+		{name: "map in singular", msg: func() *dynamicpb.Message {
+			return withChild(plain(), withByName(plain(), plain(), leaf(), leaf(), leaf()))
+		}},
+		{name: "all kinds", msg: func() *dynamicpb.Message {
+			return withChild(withChildren(withByName(plain(), leaf()), leaf()), leaf())
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// map order is random, so walk a few times
+			for i := 0; i < 10; i++ {
+				msg := tc.msg()
+				for stopAfter := 1; stopAfter <= 3; stopAfter++ {
+					calls := 0
+					ForEachUnknownField(msg, func(protoreflect.Message) bool {
+						calls++
+						return calls < stopAfter
+					})
+					require.Equal(t, stopAfter, calls, "stopping after %d messages", stopAfter)
+				}
+				calls := 0
+				ForEachUnknownField(msg, func(protoreflect.Message) bool {
+					calls++
+					return true
+				})
+				require.Equal(t, 3, calls)
+				require.Len(t, NewReport(msg).Fields, 3)
+				require.True(t, MessageHasUnknownFields(msg))
+				DropUnknownFields(msg)
+				require.False(t, MessageHasUnknownFields(msg))
+			}
+		})
+	}
+}
+
+func BenchmarkWalk(b *testing.B) {
+	md := benchNodeDescriptor(b)
+	for _, bc := range []struct {
+		name string
+		msg  protoreflect.Message
+	}{
+		{name: "deep", msg: deepBenchNode(md, 100)},
+		{name: "wide", msg: wideBenchNode(md, 500)},
+	} {
+		b.Run(bc.name+"/range", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rangeWalk(bc.msg, func(protoreflect.Message) bool {
+					b.Fatal("unexpected unknown fields")
+					return false
+				})
+			}
+		})
+		b.Run(bc.name+"/plan", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if MessageHasUnknownFields(bc.msg) {
+					b.Fatal("unexpected unknown fields")
+				}
+			}
+		})
+	}
+}